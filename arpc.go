@@ -2,12 +2,17 @@ package arpc
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -45,18 +50,48 @@ type Validatable interface {
 	Valid() error
 }
 
+// ContextValidatable interface, use for validation that requires request context
+type ContextValidatable interface {
+	Valid(ctx context.Context) error
+}
+
 type Mux interface {
 	Handle(pattern string, handler http.Handler)
 }
 
 type Manager struct {
-	Decoder      Decoder
-	Encoder      Encoder
-	ErrorEncoder ErrorEncoder
-	Validate     bool // set to true to validate request after decode using Validatable interface
-	onErrorFuncs []func(http.ResponseWriter, *http.Request, any, error)
-	onOKFuncs    []func(http.ResponseWriter, *http.Request, any, any)
-	WrapError    func(error) error
+	Decoder          Decoder
+	Encoder          Encoder
+	ErrorEncoder     ErrorEncoder
+	Validate         bool     // set to true to validate request after decode using validate tags and Validatable interface
+	ValidateTag      string   // struct tag for validate rules, empty is validate, must set before create handlers
+	AllowedMethods   []string // allowed request methods, empty allows all methods
+	DefaultMediaType string   // response media type when client accepts any, empty is application/json
+	codecs           map[string]Codec
+	encodeTypes      []string
+	acceptProblem    bool // accepts application/problem+json, set by ProblemEncoder
+	rules            map[string]RuleFunc
+	validateCache    sync.Map // map[reflect.Type][]validateField
+	validateFuncs    []func(context.Context, any) error
+	Recover          bool // set to true to recover panic in handler and middleware as internal error
+	onErrorFuncs     []func(http.ResponseWriter, *http.Request, any, error)
+	onOKFuncs        []func(http.ResponseWriter, *http.Request, any, any)
+	WrapError        func(error) error
+
+	onBeforeWriteFuncs []func(http.ResponseWriter, *http.Request, any, any)
+
+	// ErrorStatus maps error to 200, 400 or 500 status,
+	// returns 0 to use default mapping from OKError and ProtocolError
+	ErrorStatus func(err error) int
+
+	// ErrorID generates ID for internal error i.e. arpc.NewErrorID,
+	// the ID is sent to client in error body and X-Error-Id header,
+	// OnError hooks receive *InternalError
+	ErrorID func() string
+
+	// SSEHeartbeat is the interval to send comment to idle sse stream,
+	// zero disables heartbeat
+	SSEHeartbeat time.Duration
 }
 
 // New creates new arpc manager
@@ -99,15 +134,22 @@ func (m *Manager) OnError(f func(w http.ResponseWriter, r *http.Request, req any
 	m.onErrorFuncs = append(m.onErrorFuncs, f)
 }
 
-// OnOK calls f before encode ok response
+// OnValidate calls f to validate every decoded request after type's validation
+func (m *Manager) OnValidate(f func(ctx context.Context, req any) error) {
+	m.validateFuncs = append(m.validateFuncs, f)
+}
+
+// OnOK calls f after encode ok response,
+// use OnBeforeWrite to modify response headers
 func (m *Manager) OnOK(f func(w http.ResponseWriter, r *http.Request, req any, res any)) {
 	m.onOKFuncs = append(m.onOKFuncs, f)
 }
 
 func (m *Manager) Encode(w http.ResponseWriter, r *http.Request, v any) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(struct {
+	mt, c := m.responseCodec(r)
+	w.Header().Set("Content-Type", contentType(mt))
+	w.WriteHeader(resultStatus(v))
+	c.Encode(w, struct {
 		OK     bool `json:"ok"`
 		Result any  `json:"result"`
 	}{true, v})
@@ -118,33 +160,22 @@ func (m *Manager) Decode(r *http.Request, v any) error {
 		p.AdaptRequest(r)
 	}
 
-	switch r.Method {
-	case http.MethodGet:
+	if !decodeBody(r) {
 		if v, ok := v.(FormUnmarshaler); ok {
 			return WrapError(v.UnmarshalForm(r.Form))
 		}
+		if bindable(v) {
+			query := r.URL.Query()
+			return bindValues(v, query, query)
+		}
 		return nil
-	case http.MethodPost:
-		mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
-		switch mt {
-		case "application/json":
-			return WrapError(json.NewDecoder(r.Body).Decode(v))
-		case "application/x-www-form-urlencoded":
-			err := r.ParseForm()
-			if err != nil {
-				return WrapError(err)
-			}
-			if v, ok := v.(FormUnmarshaler); ok {
-				return WrapError(v.UnmarshalForm(r.PostForm))
-			}
-		case "multipart/form-data":
-			err := r.ParseMultipartForm(32 << 20)
-			if err != nil {
-				return WrapError(err)
-			}
-			if v, ok := v.(MultipartFormUnmarshaler); ok {
-				return WrapError(v.UnmarshalMultipartForm(r.MultipartForm))
-			}
+	}
+
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if c := m.requestCodec(mt); c != nil {
+		err := c.Decode(r, v)
+		if err != ErrUnsupported {
+			return WrapError(err)
 		}
 	}
 
@@ -156,21 +187,103 @@ func (m *Manager) Decode(r *http.Request, v any) error {
 	return ErrUnsupported
 }
 
-func (m *Manager) EncodeError(w http.ResponseWriter, r *http.Request, err error) {
-	var status int
-	switch err.(type) {
-	case OKError:
-		status = http.StatusOK
-	case *ProtocolError:
-		status = http.StatusBadRequest
+// decodeBody returns true if request should be decoded from body,
+// POST, PUT and PATCH always have body, GET and HEAD decode from query,
+// other methods decode body only when request sent it
+func decodeBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	case http.MethodGet, http.MethodHead:
+		return false
+	}
+	return r.ContentLength != 0 || r.Header.Get("Content-Type") != ""
+}
+
+// parsePostForm parses url-encoded body into r.PostForm for any method,
+// net/http parses body only for POST, PUT and PATCH
+func parsePostForm(r *http.Request) error {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.ParseForm()
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	r.PostForm, err = url.ParseQuery(string(b))
+	return err
+}
+
+// errorStatus classifies err into 200, 400 or 500 status,
+// returns the status and the error to encode
+func (m *Manager) errorStatus(err error) (int, error) {
+	var (
+		okErr OKError
+		pErr  *ProtocolError
+	)
+	isOK := errors.As(err, &okErr)
+	isProtocol := errors.As(err, &pErr)
+
+	status := 0
+	if m.ErrorStatus != nil {
+		status = m.ErrorStatus(err)
+	}
+	if status == 0 {
+		switch {
+		case isOK:
+			status = http.StatusOK
+		case isProtocol:
+			status = http.StatusBadRequest
+		default:
+			status = http.StatusInternalServerError
+		}
+	}
+
+	switch status {
+	case http.StatusOK:
+		if isOK {
+			return status, okErr.(error)
+		}
+		return status, wrapError(err)
+	case http.StatusBadRequest:
+		if isProtocol {
+			return status, pErr
+		}
+		return status, &ProtocolError{Message: err.Error()}
 	default:
-		status = http.StatusInternalServerError
-		err = internalError{}
+		var iErr *InternalError
+		if errors.As(err, &iErr) {
+			return http.StatusInternalServerError, iErr
+		}
+		return http.StatusInternalServerError, internalError{}
 	}
+}
+
+// withErrorID wraps internal error with InternalError when ErrorID is set
+func (m *Manager) withErrorID(err error) error {
+	if m.ErrorID == nil {
+		return err
+	}
+	var iErr *InternalError
+	if errors.As(err, &iErr) {
+		return err
+	}
+	if status, _ := m.errorStatus(err); status != http.StatusInternalServerError {
+		return err
+	}
+	return &InternalError{ID: m.ErrorID(), Err: err}
+}
+
+func (m *Manager) EncodeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, err := m.errorStatus(err)
+	err = localizeError(r, err)
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	mt, c := m.responseCodec(r)
+	w.Header().Set("Content-Type", contentType(mt))
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(struct {
+	c.Encode(w, struct {
 		OK    bool `json:"ok"`
 		Error any  `json:"error"`
 	}{false, err})
@@ -192,6 +305,7 @@ const (
 	miRequest                    // *http.Request
 	miResponseWriter             // http.ResponseWriter
 	miSSEResponseWriter          // SSEResponseWriter
+	miSSESend                    // func(T) error
 	miAny                        // any
 	miError                      // error
 )
@@ -211,153 +325,293 @@ func setOrPanic(m map[mapIndex]int, k mapIndex, v int) {
 	m[k] = v
 }
 
+// checkRequest checks request before decode,
+// raw skips accept negotiation for handler that does not encode result
+func (m *Manager) checkRequest(w http.ResponseWriter, r *http.Request, raw bool) error {
+	if len(m.AllowedMethods) > 0 && !slices.Contains(m.AllowedMethods, r.Method) {
+		w.Header().Set("Allow", strings.Join(m.AllowedMethods, ", "))
+		return ErrMethodNotAllowed
+	}
+	if m.Encoder == nil && !raw {
+		_, err := m.negotiate(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *Manager) encodeAndHookError(w http.ResponseWriter, r *http.Request, req any, err error) {
-	err = m.wrapError(err)
+	err = m.withErrorID(m.wrapError(err))
 
+	var iErr *InternalError
+	if errors.As(err, &iErr) {
+		w.Header().Set("X-Error-Id", iErr.ID)
+	}
 	m.errorEncoder()(w, r, err)
+	m.hookError(w, r, req, err)
+}
 
+func (m *Manager) hookError(w http.ResponseWriter, r *http.Request, req any, err error) {
 	for _, f := range m.onErrorFuncs {
 		f(w, r, req, err)
 	}
 }
 
-func (m *Manager) Handler(f any) http.Handler {
-	hasWriter := false
+// decodeRequest decodes r into req then validates it
+func (m *Manager) decodeRequest(r *http.Request, req any) error {
+	err := m.decoder()(r, req)
+	if err != nil {
+		return err
+	}
+	return m.validateRequest(r.Context(), req)
+}
+
+// validateRequest validates decoded request
+func (m *Manager) validateRequest(ctx context.Context, req any) error {
+	if !m.Validate {
+		return nil
+	}
+
+	err := m.validateTags(req)
+	if err != nil {
+		return err
+	}
 
+	switch req := req.(type) {
+	case Validatable:
+		err = req.Valid()
+	case ContextValidatable:
+		err = req.Valid(ctx)
+	}
+	if err != nil {
+		return toValidationError(err)
+	}
+
+	for _, f := range m.validateFuncs {
+		err = f(ctx, req)
+		if err != nil {
+			return toValidationError(err)
+		}
+	}
+	return nil
+}
+
+// encodeResult writes result headers, runs before write hooks,
+// encodes res then runs ok hooks, stream result sends as raw body
+func (m *Manager) encodeResult(w http.ResponseWriter, r *http.Request, req, res any) {
+	switch res.(type) {
+	case StreamResult, fs.File:
+		m.serveStream(w, r, req, res)
+		return
+	}
+
+	writeResultHeaders(w, res)
+	m.hookBeforeWrite(w, r, req, res)
+	m.encoder()(w, r, res)
+	m.hookOK(w, r, req, res)
+}
+
+func (m *Manager) hookOK(w http.ResponseWriter, r *http.Request, req, res any) {
+	for _, f := range m.onOKFuncs {
+		f(w, r, req, res)
+	}
+}
+
+// handlerFunc is the parsed function that can be called by arpc
+type handlerFunc struct {
+	fv        reflect.Value
+	numIn     int
+	mapIn     map[mapIndex]int
+	mapOut    map[mapIndex]int
+	hasWriter bool
+	isStream  bool // result sends as stream
+	isChan    bool // result is channel sends as sse events
+	infType   reflect.Type
+	infPtr    bool
+}
+
+func parseHandlerFunc(f any) *handlerFunc {
 	fv := reflect.ValueOf(f)
 	ft := fv.Type()
 	if ft.Kind() != reflect.Func {
 		panic("arpc: f must be a function")
 	}
 
+	fn := handlerFunc{fv: fv}
+
 	// build mapIn
-	numIn := ft.NumIn()
-	mapIn := make(map[mapIndex]int)
-	for i := 0; i < numIn; i++ {
+	fn.numIn = ft.NumIn()
+	fn.mapIn = make(map[mapIndex]int)
+	for i := 0; i < fn.numIn; i++ {
 		fi := ft.In(i)
 
 		// assume this is grpc call options
-		if fi.Kind() == reflect.Slice && i == numIn-1 {
-			numIn--
+		if fi.Kind() == reflect.Slice && i == fn.numIn-1 {
+			fn.numIn--
 			break
 		}
 
+		if isSSESendFunc(fi) {
+			setOrPanic(fn.mapIn, miSSESend, i)
+			fn.hasWriter = true
+			continue
+		}
+
 		switch fi.String() {
 		case strContext:
-			setOrPanic(mapIn, miContext, i)
+			setOrPanic(fn.mapIn, miContext, i)
 		case strRequest:
-			setOrPanic(mapIn, miRequest, i)
+			setOrPanic(fn.mapIn, miRequest, i)
 		case strResponseWriter:
-			setOrPanic(mapIn, miResponseWriter, i)
-			hasWriter = true
+			setOrPanic(fn.mapIn, miResponseWriter, i)
+			fn.hasWriter = true
 		case strSSEResponseWriter:
-			setOrPanic(mapIn, miSSEResponseWriter, i)
-			hasWriter = true
+			setOrPanic(fn.mapIn, miSSEResponseWriter, i)
+			fn.hasWriter = true
 		default:
-			setOrPanic(mapIn, miAny, i)
+			setOrPanic(fn.mapIn, miAny, i)
 		}
 	}
 
 	// build mapOut
 	numOut := ft.NumOut()
-	mapOut := make(map[mapIndex]int)
+	fn.mapOut = make(map[mapIndex]int)
 	for i := 0; i < numOut; i++ {
 		switch ft.Out(i).String() {
 		case strError:
-			setOrPanic(mapOut, miError, i)
+			setOrPanic(fn.mapOut, miError, i)
 		default:
-			setOrPanic(mapOut, miAny, i)
+			setOrPanic(fn.mapOut, miAny, i)
+			fn.isStream = isStreamType(ft.Out(i))
+			fn.isChan = ft.Out(i).Kind() == reflect.Chan && ft.Out(i).ChanDir()&reflect.RecvDir != 0
 		}
 	}
 
-	var (
-		infType reflect.Type
-		infPtr  bool
-	)
-	if i, ok := mapIn[miAny]; ok {
-		infType = ft.In(i)
-		if infType.Kind() == reflect.Ptr {
-			infType = infType.Elem()
-			infPtr = true
+	if i, ok := fn.mapIn[miAny]; ok {
+		fn.infType = ft.In(i)
+		if fn.infType.Kind() == reflect.Ptr {
+			fn.infType = fn.infType.Elem()
+			fn.infPtr = true
 		}
 	}
 
-	encoder := m.encoder()
-	decoder := m.decoder()
+	return &fn
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var (
-			req any
-			res any
-		)
-
-		vIn := make([]reflect.Value, numIn)
-		// inject context
-		if i, ok := mapIn[miContext]; ok {
-			vIn[i] = reflect.ValueOf(r.Context())
-		}
-		// inject request interface
-		if i, ok := mapIn[miAny]; ok {
-			rfReq := reflect.New(infType)
-			req = rfReq.Interface()
-			err := decoder(r, req)
-			if err != nil {
-				m.encodeAndHookError(w, r, req, err)
-				return
-			}
+// hasRequest returns true if function takes request interface
+func (fn *handlerFunc) hasRequest() bool {
+	return fn.infType != nil
+}
 
-			if m.Validate {
-				if req, ok := req.(Validatable); ok {
-					err = req.Valid()
-					if err != nil {
-						m.encodeAndHookError(w, r, req, err)
-						return
-					}
-				}
-			}
-			if infPtr {
-				vIn[i] = rfReq
-			} else {
-				vIn[i] = rfReq.Elem()
-			}
-		}
-		// inject request
-		if i, ok := mapIn[miRequest]; ok {
-			vIn[i] = reflect.ValueOf(r)
+// hasResult returns true if function returns result
+func (fn *handlerFunc) hasResult() bool {
+	_, ok := fn.mapOut[miAny]
+	return ok
+}
+
+// newRequest allocates new request for the function
+func (fn *handlerFunc) newRequest() reflect.Value {
+	return reflect.New(fn.infType)
+}
+
+// call calls the function,
+// result will be an empty object if the function does not return result and does not use the writer
+func (fn *handlerFunc) call(m *Manager, w http.ResponseWriter, r *http.Request, rfReq reflect.Value) (res any, err error) {
+	vIn := make([]reflect.Value, fn.numIn)
+	// inject context
+	if i, ok := fn.mapIn[miContext]; ok {
+		vIn[i] = reflect.ValueOf(r.Context())
+	}
+	// inject request interface
+	if i, ok := fn.mapIn[miAny]; ok {
+		if fn.infPtr {
+			vIn[i] = rfReq
+		} else {
+			vIn[i] = rfReq.Elem()
 		}
-		// inject response writer
-		if i, ok := mapIn[miResponseWriter]; ok {
-			vIn[i] = reflect.ValueOf(w)
+	}
+	// inject request
+	if i, ok := fn.mapIn[miRequest]; ok {
+		vIn[i] = reflect.ValueOf(r)
+	}
+	// inject response writer
+	if i, ok := fn.mapIn[miResponseWriter]; ok {
+		vIn[i] = reflect.ValueOf(w)
+	}
+	// inject sse response writer and send function
+	_, hasSSE := fn.mapIn[miSSEResponseWriter]
+	_, hasSend := fn.mapIn[miSSESend]
+	var sw *sseResponseWriter
+	if hasSSE || hasSend {
+		sw = newSSEResponseWriter(w, r, m.SSEHeartbeat)
+		defer sw.close()
+		if i, ok := fn.mapIn[miSSEResponseWriter]; ok {
+			vIn[i] = reflect.ValueOf(SSEResponseWriter(sw))
 		}
-		// inject sse response writer
-		if i, ok := mapIn[miSSEResponseWriter]; ok {
-			vIn[i] = reflect.ValueOf(newSSEResponseWriter(w))
+		if i, ok := fn.mapIn[miSSESend]; ok {
+			vIn[i] = sw.sendFunc(r.Context(), fn.fv.Type().In(i))
 		}
+	}
 
-		vOut := fv.Call(vIn)
-		// check error
-		if i, ok := mapOut[miError]; ok {
-			if vErr := vOut[i]; !vErr.IsNil() {
-				if err, ok := vErr.Interface().(error); ok && err != nil {
-					m.encodeAndHookError(w, r, req, err)
-					return
+	vOut := fn.fv.Call(vIn)
+	// check error
+	if i, ok := fn.mapOut[miError]; ok {
+		if vErr := vOut[i]; !vErr.IsNil() {
+			if err, ok := vErr.Interface().(error); ok && err != nil {
+				// error before the first write still can be encoded
+				if sw != nil && sw.end() {
+					return nil, &startedError{err}
 				}
+				return nil, err
 			}
 		}
+	}
 
-		// check response
-		if i, ok := mapOut[miAny]; ok {
-			res = vOut[i].Interface()
-			encoder(w, r, res)
-		} else if !hasWriter {
-			res = _empty
-			encoder(w, r, res)
-		}
+	// check response
+	if i, ok := fn.mapOut[miAny]; ok {
+		return vOut[i].Interface(), nil
+	}
+	if !fn.hasWriter {
+		return _empty, nil
+	}
+	return nil, nil
+}
+
+func (m *Manager) Handler(f any) http.Handler {
+	return m.handler(parseHandlerFunc(f))
+}
 
-		// run ok hooks
-		for _, f := range m.onOKFuncs {
-			f(w, r, req, res)
+func (m *Manager) handler(fn *handlerFunc) http.Handler {
+	p := &pipeline{
+		raw: fn.hasWriter || fn.isStream || fn.isChan,
+		call: func(w http.ResponseWriter, r *http.Request, req any) (any, error) {
+			var rfReq reflect.Value
+			if req != nil {
+				rfReq = reflect.ValueOf(req)
+			}
+			return fn.call(m, w, r, rfReq)
+		},
+		write: func(w http.ResponseWriter, r *http.Request, req, res any) {
+			if fn.hasWriter && !fn.hasResult() {
+				m.hookOK(w, r, req, nil)
+				return
+			}
+			if fn.isChan {
+				m.serveEvents(w, r, req, res)
+				return
+			}
+			m.encodeResult(w, r, req, res)
+		},
+	}
+	if fn.hasRequest() {
+		p.newRequest = func() any { return fn.newRequest().Interface() }
+		if m.Validate {
+			m.checkValidate(fn.infType, map[reflect.Type]bool{})
 		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serve(w, r, p)
 	})
 }
 
@@ -415,6 +669,12 @@ type Middleware func(r *MiddlewareContext) error
 func (m *Manager) Middleware(f Middleware) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m.Recover {
+				rw := &recoverResponseWriter{ResponseWriter: w}
+				w = rw
+				defer m.recoverPanic(rw, r, new(any))
+			}
+
 			ctx := MiddlewareContext{r, w}
 			err := f(&ctx)
 			if err != nil {
@@ -1,14 +1,26 @@
 package arpc_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -79,6 +91,63 @@ func TestOnOK(t *testing.T) {
 	})
 }
 
+type createdResult struct {
+	ID string `json:"id"`
+}
+
+func (res *createdResult) ResponseStatus() int {
+	return http.StatusCreated
+}
+
+func (res *createdResult) ResponseHeaders() http.Header {
+	return http.Header{"Location": {"/users/" + res.ID}}
+}
+
+func (res *createdResult) ResponseCookies() []*http.Cookie {
+	return []*http.Cookie{{Name: "last", Value: res.ID}}
+}
+
+func TestResultResponse(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.OnBeforeWrite(func(w http.ResponseWriter, r *http.Request, req, res any) {
+		w.Header().Set("Cache-Control", "no-store")
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		h := m.Handler(func() (*createdResult, error) {
+			return &createdResult{ID: "1"}, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/users/1", resp.Header.Get("Location"))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+		if assert.Len(t, resp.Cookies(), 1) {
+			assert.Equal(t, "1", resp.Cookies()[0].Value)
+		}
+		assert.JSONEq(t, `{"ok":true,"result":{"id":"1"}}`, w.Body.String())
+	})
+
+	t.Run("Handle", func(t *testing.T) {
+		h := arpc.HandleNoRequest(m, func(ctx context.Context) (*createdResult, error) {
+			return &createdResult{ID: "2"}, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "/users/2", resp.Header.Get("Location"))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	})
+}
+
 func TestInvalidContentType(t *testing.T) {
 	t.Parallel()
 
@@ -137,6 +206,23 @@ func TestError(t *testing.T) {
 		assert.JSONEq(t, `{"ok":false,"error":{"code":"0001","message":"some error"}}`, w.Body.String())
 	})
 
+	t.Run("Details", func(t *testing.T) {
+		cause := errors.New("version mismatch")
+		err := arpc.NewErrorDetails("CONFLICT", "conflict", map[string]any{"id": "u1"}).(*arpc.Error).WithCause(cause)
+		assert.True(t, errors.Is(err, cause))
+		assert.Equal(t, map[string]any{"id": "u1"}, err.Details())
+
+		h := m.Handler(func() error {
+			return fmt.Errorf("update: %w", err)
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{"code":"CONFLICT","message":"conflict","details":{"id":"u1"}}}`, w.Body.String())
+	})
+
 	t.Run("CustomError", func(t *testing.T) {
 		h := m.Handler(func() error {
 			return &customError{"1A475"}
@@ -243,6 +329,248 @@ func TestManager_WrapError(t *testing.T) {
 	assert.JSONEq(t, `{"ok":false,"error":{"code":"1000","message":"some error"}}`, w.Body.String())
 }
 
+func TestErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	errForbidden := errors.New("forbidden")
+	errBadInput := errors.New("bad input")
+
+	m := arpc.New()
+	m.ErrorStatus = func(err error) int {
+		switch {
+		case errors.Is(err, errForbidden):
+			return http.StatusOK
+		case errors.Is(err, errBadInput):
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+
+	cases := []struct {
+		Name   string
+		Err    error
+		Status int
+		Body   string
+	}{
+		{"Wrapped Error", fmt.Errorf("get user: %w", arpc.NewErrorCode("1000", "not found")), http.StatusOK, `{"ok":false,"error":{"code":"1000","message":"not found"}}`},
+		{"Wrapped OKError", fmt.Errorf("get user: %w", &customError{"1A475"}), http.StatusOK, `{"ok":false,"error":{"code":"1A475"}}`},
+		{"Wrapped ProtocolError", fmt.Errorf("parse: %w", &arpc.ProtocolError{Message: "invalid"}), http.StatusBadRequest, `{"ok":false,"error":{"message":"invalid"}}`},
+		{"Mapped OK", fmt.Errorf("check: %w", errForbidden), http.StatusOK, `{"ok":false,"error":{"message":"check: forbidden"}}`},
+		{"Mapped BadRequest", errBadInput, http.StatusBadRequest, `{"ok":false,"error":{"message":"bad input"}}`},
+		{"Internal", fmt.Errorf("db: %w", errors.New("timeout")), http.StatusInternalServerError, `{"ok":false,"error":{}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := m.Handler(func() error {
+				return tc.Err
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/", nil)
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.Status, w.Code)
+			assert.JSONEq(t, tc.Body, w.Body.String())
+		})
+	}
+}
+
+func TestProblemEncoder(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.ErrorEncoder = m.ProblemEncoder(0)
+
+	cases := []struct {
+		Name   string
+		Err    error
+		Status int
+		Body   string
+	}{
+		{"Error", arpc.NewErrorCode("1000", "not found"), http.StatusOK, `{"title":"OK","status":200,"detail":"not found","instance":"/user","code":"1000","message":"not found"}`},
+		{"ValidationError", arpc.NewValidationError(&arpc.FieldError{Field: "name", Code: "required", Message: "required"}), http.StatusOK, `{"title":"OK","status":200,"detail":"required","instance":"/user","code":"validation","message":"required","fields":[{"field":"name","code":"required","message":"required"}]}`},
+		{"ProtocolError", arpc.ErrUnsupported, http.StatusBadRequest, `{"title":"Bad Request","status":400,"detail":"unsupported content type","instance":"/user","message":"unsupported content type"}`},
+		{"Internal", fmt.Errorf("db down"), http.StatusInternalServerError, `{"title":"Internal Server Error","status":500,"instance":"/user"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := m.Handler(func() error {
+				return tc.Err
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/user", nil)
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.Status, w.Code)
+			assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.Body, w.Body.String())
+
+			p, err := arpc.ParseProblem(w.Body.Bytes())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.Status, p.Status)
+			}
+		})
+	}
+
+	t.Run("Accept", func(t *testing.T) {
+		h := m.Handler(func() error {
+			return arpc.NewErrorCode("1000", "not found")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/user", nil)
+		r.Header.Set("Accept", "application/problem+json")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		p, err := arpc.ParseProblem(w.Body.Bytes())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "1000", p.Code)
+		}
+
+		h = m.Handler(func() (string, error) {
+			return "ok", nil
+		})
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("POST", "/user", nil)
+		r.Header.Set("Accept", "application/problem+json")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":true,"result":"ok"}`, w.Body.String())
+	})
+
+	t.Run("OKStatus", func(t *testing.T) {
+		m := arpc.New()
+		m.ErrorEncoder = m.ProblemEncoder(http.StatusUnprocessableEntity)
+		h := m.Handler(func() error {
+			return arpc.NewErrorCode("1000", "not found")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		p, err := arpc.ParseProblem(w.Body.Bytes())
+		if assert.NoError(t, err) {
+			var e *arpc.Error
+			if assert.True(t, errors.As(p.Err(), &e)) {
+				assert.Equal(t, "1000", e.Code())
+				assert.Equal(t, "not found", e.Message())
+			}
+		}
+	})
+}
+
+func TestErrorID(t *testing.T) {
+	t.Parallel()
+
+	errDB := errors.New("db down")
+
+	var hookErr error
+	m := arpc.New()
+	m.ErrorID = func() string { return "abc" }
+	m.OnError(func(w http.ResponseWriter, r *http.Request, req any, err error) {
+		hookErr = err
+	})
+
+	t.Run("Internal", func(t *testing.T) {
+		h := m.Handler(func() error {
+			return fmt.Errorf("get user: %w", errDB)
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "abc", w.Header().Get("X-Error-Id"))
+		assert.JSONEq(t, `{"ok":false,"error":{"id":"abc"}}`, w.Body.String())
+
+		var iErr *arpc.InternalError
+		if assert.True(t, errors.As(hookErr, &iErr)) {
+			assert.Equal(t, "abc", iErr.ID)
+		}
+		assert.True(t, errors.Is(hookErr, errDB))
+	})
+
+	t.Run("OKError", func(t *testing.T) {
+		h := m.Handler(func() error {
+			return arpc.NewError("not found")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-Error-Id"))
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"not found"}}`, w.Body.String())
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	catalog := arpc.NewCatalog()
+	errUserNotFound := catalog.Register("USER_NOT_FOUND", "user {id} not found").
+		Locale("th", "ไม่พบผู้ใช้ {id}")
+	catalog.Register("FORBIDDEN", "forbidden")
+
+	m := arpc.New()
+	h := m.Handler(func() error {
+		return fmt.Errorf("get user: %w", errUserNotFound.New(map[string]any{"id": 7}))
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{"code":"USER_NOT_FOUND","message":"user 7 not found"}}`, w.Body.String())
+	})
+
+	t.Run("AcceptLanguage", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Language", "fr;q=0.9, th-TH")
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":false,"error":{"code":"USER_NOT_FOUND","message":"ไม่พบผู้ใช้ 7"}}`, w.Body.String())
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept-Language", "en")
+		r = r.WithContext(arpc.WithLocale(r.Context(), "th"))
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":false,"error":{"code":"USER_NOT_FOUND","message":"ไม่พบผู้ใช้ 7"}}`, w.Body.String())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		err := errUserNotFound.New(map[string]any{"id": 7})
+		var e *arpc.Error
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "USER_NOT_FOUND", e.Code())
+			assert.Equal(t, "user 7 not found", e.Message())
+		}
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		b, err := json.Marshal(catalog)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"USER_NOT_FOUND": {"message":"user {id} not found","locales":{"th":"ไม่พบผู้ใช้ {id}"}},
+			"FORBIDDEN": {"message":"forbidden"}
+		}`, string(b))
+
+		ec, ok := catalog.Lookup("FORBIDDEN")
+		if assert.True(t, ok) {
+			assert.Equal(t, "forbidden", ec.Message("th", nil))
+		}
+	})
+}
+
 func TestMiddleware(t *testing.T) {
 	t.Parallel()
 
@@ -303,3 +631,1586 @@ func TestSSE(t *testing.T) {
 	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
 	assert.Equal(t, "data: 1\n\n", w.Body.String())
 }
+
+func TestSSEAccept(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+
+	t.Run("Writer", func(t *testing.T) {
+		h := m.Handler(f3)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequestWithContext(ctx, "GET", "/", nil)
+		r.Header.Set("Accept", "text/event-stream")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("Channel", func(t *testing.T) {
+		h := m.Handler(func() (<-chan tick, error) {
+			ch := make(chan tick, 1)
+			ch <- tick{1}
+			close(ch)
+			return ch, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", "text/event-stream")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id: 1\nevent: tick\ndata: {\"n\":1}\n\n", w.Body.String())
+	})
+
+	t.Run("Encode", func(t *testing.T) {
+		h := m.Handler(f1)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"a": 2, "b": 3}`)))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "text/event-stream")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestSSEMessage(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+
+	t.Run("Fields", func(t *testing.T) {
+		h := m.Handler(func(w arpc.SSEResponseWriter) error {
+			w.WriteComment("hello")
+			w.WriteMessage(arpc.SSEMessage{ID: "1", Event: "update", Data: "a\nb\r\nc", Retry: 3 * time.Second})
+			w.WriteMessage(arpc.SSEMessage{ID: "2"})
+			w.WriteEvent("ping", "")
+			w.WriteData("x\ry")
+			return nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, ": hello\n\n"+
+			"id: 1\nevent: update\nretry: 3000\ndata: a\ndata: b\ndata: c\n\n"+
+			"id: 2\n\n"+
+			"event: ping\ndata: \n\n"+
+			"data: x\ndata: y\n\n", w.Body.String())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		h := m.Handler(func(w arpc.SSEResponseWriter) error {
+			assert.ErrorIs(t, w.WriteMessage(arpc.SSEMessage{ID: "1\ndata: x"}), arpc.ErrSSEInvalidField)
+			assert.ErrorIs(t, w.WriteEvent("a\rb", "x"), arpc.ErrSSEInvalidField)
+			return nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Empty(t, w.Body.String())
+	})
+}
+
+func TestSSEReplay(t *testing.T) {
+	t.Parallel()
+
+	replay := arpc.NewSSEReplay(3)
+	for i := 1; i <= 4; i++ {
+		replay.Add("news", arpc.SSEMessage{Data: strconv.Itoa(i)})
+	}
+
+	t.Run("Since", func(t *testing.T) {
+		msgs := replay.Since("news", "3")
+		if assert.Len(t, msgs, 1) {
+			assert.Equal(t, arpc.SSEMessage{ID: "4", Data: "4"}, msgs[0])
+		}
+		// id 1 already dropped, replay all buffered messages
+		assert.Len(t, replay.Since("news", "1"), 3)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		assert.Nil(t, replay.Since("unknown", "1"))
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		replay := arpc.NewSSEReplay(3)
+		replay.Add("user:1", arpc.SSEMessage{Data: "1"})
+
+		started := make(chan struct{})
+		h := arpc.New().Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+			return replay.Serve(ctx, notifyHeader{w, started}, "user:1")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		done := make(chan struct{})
+		go func() {
+			h.ServeHTTP(w, r)
+			close(done)
+		}()
+
+		<-started
+		replay.Remove("user:1")
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stream not ended")
+		}
+		assert.Nil(t, replay.Since("user:1", ""))
+	})
+
+	m := arpc.New()
+	srv := httptest.NewServer(m.Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+		return replay.Serve(ctx, w, "news")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"?lastEventId=2", nil)
+	r.Header.Set("Last-Event-ID", "3")
+	resp, err := http.DefaultClient.Do(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	replay.Add("news", arpc.SSEMessage{Data: "5"})
+
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < 2 && scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	assert.Equal(t, []string{"4", "5"}, ids)
+}
+
+// notifyHeader closes ch when header written
+type notifyHeader struct {
+	arpc.SSEResponseWriter
+	ch chan struct{}
+}
+
+func (w notifyHeader) WriteHeader(statusCode int) {
+	w.SSEResponseWriter.WriteHeader(statusCode)
+	close(w.ch)
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.SSEHeartbeat = 10 * time.Millisecond
+	srv := httptest.NewServer(m.Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+		w.WriteData("1")
+		w.Flush()
+		<-ctx.Done()
+		return nil
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	resp, err := http.DefaultClient.Do(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if scanner.Text() != "" {
+			lines = append(lines, scanner.Text())
+		}
+		if len(lines) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"data: 1", ": heartbeat", ": heartbeat"}, lines)
+}
+
+func TestSSEHeartbeatIdle(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.SSEHeartbeat = 20 * time.Millisecond
+	srv := httptest.NewServer(m.Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+		<-ctx.Done()
+		return nil
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	resp, err := http.DefaultClient.Do(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	scanner := bufio.NewScanner(resp.Body)
+	if assert.True(t, scanner.Scan()) {
+		assert.Equal(t, ": heartbeat", scanner.Text())
+	}
+}
+
+func TestSSEError(t *testing.T) {
+	t.Parallel()
+
+	newManager := func(hooked *error) *arpc.Manager {
+		m := arpc.New()
+		m.SSEHeartbeat = time.Minute
+		m.OnError(func(w http.ResponseWriter, r *http.Request, req any, err error) {
+			*hooked = err
+		})
+		return m
+	}
+
+	t.Run("BeforeWrite", func(t *testing.T) {
+		var hooked error
+		h := newManager(&hooked).Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+			return arpc.NewError("unauthorized")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"unauthorized"}}`, w.Body.String())
+		assert.EqualError(t, hooked, "unauthorized")
+	})
+
+	t.Run("AfterWrite", func(t *testing.T) {
+		var hooked error
+		h := newManager(&hooked).Handler(func(w arpc.SSEResponseWriter) error {
+			w.WriteData("1")
+			return arpc.NewError("closed")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "data: 1\n\n", w.Body.String())
+		assert.EqualError(t, hooked, "closed")
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		var hooked error
+		h := newManager(&hooked).Handler(func(w arpc.SSEResponseWriter) error {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusAccepted)
+			return w.WriteData("1")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+		assert.Equal(t, "no", w.Header().Get("X-Accel-Buffering"))
+		assert.Equal(t, "data: 1\n\n", w.Body.String())
+		assert.NoError(t, hooked)
+	})
+}
+
+type tick struct {
+	N int `json:"n"`
+}
+
+func (t tick) SSEEvent() (event, id string) {
+	return "tick", strconv.Itoa(t.N)
+}
+
+func TestSSEChannel(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+
+	t.Run("Channel", func(t *testing.T) {
+		h := m.Handler(func(ctx context.Context) (<-chan tick, error) {
+			ch := make(chan tick)
+			go func() {
+				defer close(ch)
+				for i := 1; i <= 2; i++ {
+					select {
+					case ch <- tick{i}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return ch, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "id: 1\nevent: tick\ndata: {\"n\":1}\n\nid: 2\nevent: tick\ndata: {\"n\":2}\n\n", w.Body.String())
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		h := m.Handler(func(ctx context.Context, send func(map[string]int) error) error {
+			for i := 1; i <= 2; i++ {
+				err := send(map[string]int{"n": i})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "data: {\"n\":1}\n\ndata: {\"n\":2}\n\n", w.Body.String())
+	})
+
+	t.Run("SendError", func(t *testing.T) {
+		h := m.Handler(func(ctx context.Context, send func(tick) error) error {
+			return arpc.NewError("no access")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"no access"}}`, w.Body.String())
+	})
+
+	t.Run("SendErrorAfterSend", func(t *testing.T) {
+		h := m.Handler(func(ctx context.Context, send func(tick) error) error {
+			send(tick{1})
+			return arpc.NewError("no access")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Equal(t, "id: 1\nevent: tick\ndata: {\"n\":1}\n\n", w.Body.String())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		h := m.Handler(func() (<-chan tick, error) {
+			return nil, arpc.NewError("no access")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"no access"}}`, w.Body.String())
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		h := m.Handler(func() (<-chan tick, error) {
+			return make(chan tick), nil
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequestWithContext(ctx, "GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Body.String())
+	})
+}
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+
+	t.Run("Success", func(t *testing.T) {
+		h := arpc.Handle(m, func(ctx context.Context, req *request) (*int, error) {
+			res := req.A + req.B
+			return &res, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"a": 2, "b": 3}`)))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":true,"result":5}`, w.Body.String())
+	})
+
+	t.Run("NoRequest", func(t *testing.T) {
+		h := arpc.HandleNoRequest(m, func(ctx context.Context) (*request, error) {
+			return &request{A: 1}, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":true,"result":{"a":1,"b":0}}`, w.Body.String())
+	})
+
+	t.Run("NoResult", func(t *testing.T) {
+		h := arpc.HandleNoResult(m, func(ctx context.Context, req *request) error {
+			if req.A == 0 {
+				return arpc.NewError("a required")
+			}
+			return nil
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"a": 1}`)))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"a required"}}`, w.Body.String())
+	})
+
+	t.Run("InvalidContentType", func(t *testing.T) {
+		h := arpc.Handle(m, func(ctx context.Context, req *request) (*int, error) {
+			return nil, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+type bindEmbedded struct {
+	Page int `query:"page"`
+}
+
+type bindRequest struct {
+	bindEmbedded
+	Name    string    `form:"name"`
+	Age     *int      `form:"age"`
+	Score   float64   `form:"score"`
+	Active  bool      `form:"active"`
+	Tags    []string  `form:"tag"`
+	IDs     []int64   `form:"id"`
+	Since   time.Time `form:"since"`
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	var got *bindRequest
+	h := m.Handler(func(req *bindRequest) {
+		got = req
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/?name=a&age=3&score=1.5&active=true&tag=x&tag=y&id=1&id=2&since=2020-01-02&page=4&Ignored=z", nil)
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+		if assert.NotNil(t, got) {
+			assert.Equal(t, "a", got.Name)
+			assert.Equal(t, 3, *got.Age)
+			assert.Equal(t, 1.5, got.Score)
+			assert.True(t, got.Active)
+			assert.Equal(t, []string{"x", "y"}, got.Tags)
+			assert.Equal(t, []int64{1, 2}, got.IDs)
+			assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), got.Since)
+			assert.Equal(t, 4, got.Page)
+			assert.Empty(t, got.Ignored)
+		}
+	})
+
+	t.Run("Form", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/?page=2&name=query", strings.NewReader("name=b&page=3"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+		if assert.NotNil(t, got) {
+			assert.Equal(t, "b", got.Name)
+			assert.Equal(t, 2, got.Page)
+			assert.Nil(t, got.Age)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/?age=abc", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"invalid value for field \"age\": \"abc\" is not a valid integer"}}`, w.Body.String())
+	})
+
+	t.Run("Unexported", func(t *testing.T) {
+		type request struct {
+			*bindEmbedded
+			name string `form:"name"`
+			Age  int    `form:"age"`
+		}
+		var got *request
+		h := m.Handler(func(req *request) {
+			got = req
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/?name=a&age=3&page=4", nil)
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+		if assert.NotNil(t, got) {
+			assert.Empty(t, got.name)
+			assert.Nil(t, got.bindEmbedded)
+			assert.Equal(t, 3, got.Age)
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		var got *BindCycle
+		h := m.Handler(func(req *BindCycle) {
+			got = req
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/?name=a", nil)
+		h.ServeHTTP(w, r)
+
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+		if assert.NotNil(t, got) {
+			assert.Equal(t, "a", got.Name)
+		}
+	})
+}
+
+type BindCycle struct {
+	*BindCycle
+	Name string `form:"name"`
+}
+
+func TestMethods(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	h := m.Handler(f1)
+
+	for _, method := range []string{"PUT", "PATCH", "DELETE"} {
+		t.Run(method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(method, "/", bytes.NewReader([]byte(`{"a": 2, "b": 3}`)))
+			r.Header.Set("Content-Type", "application/json")
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"ok":true,"result":5}`, w.Body.String())
+		})
+	}
+
+	t.Run("DeleteForm", func(t *testing.T) {
+		var got *bindRequest
+		h := m.Handler(func(req *bindRequest) {
+			got = req
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("DELETE", "/", strings.NewReader("name=a"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, "a", got.Name)
+		}
+	})
+
+	t.Run("DeleteWithoutBody", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("DELETE", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":true,"result":0}`, w.Body.String())
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("HEAD", "/?a=1", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestManager_AllowedMethods(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.AllowedMethods = []string{"POST", "PUT"}
+	h := m.Handler(f1)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "POST, PUT", w.Header().Get("Allow"))
+	assert.JSONEq(t, `{"ok":false,"error":{"message":"method not allowed"}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("PUT", "/", bytes.NewReader([]byte(`{"a": 1}`)))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+
+	assert.JSONEq(t, `{"ok":true,"result":1}`, w.Body.String())
+}
+
+type textCodec struct{}
+
+func (textCodec) Decode(r *http.Request, v any) error {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Sscanf(string(b), "%d %d", &v.(*request).A, &v.(*request).B)
+	return err
+}
+
+func (textCodec) Encode(w io.Writer, v any) error {
+	_, err := fmt.Fprintf(w, "%+v", v)
+	return err
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.RegisterCodec("text/plain", textCodec{})
+	h := m.Handler(f1)
+
+	t.Run("Decode", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader("2 3"))
+		r.Header.Set("Content-Type", "text/plain")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"ok":true,"result":5}`, w.Body.String())
+	})
+
+	t.Run("Accept", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":1,"b":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "application/json;q=0.5, text/plain")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "{OK:true Result:2}", w.Body.String())
+	})
+
+	t.Run("AcceptAny", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":1,"b":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "*/*")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":1,"b":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "application/xml")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"ok":false,"error":{"message":"not acceptable"}}`, w.Body.String())
+	})
+
+	t.Run("DefaultMediaType", func(t *testing.T) {
+		m := arpc.New()
+		m.RegisterCodec("text/plain", textCodec{})
+		m.DefaultMediaType = "text/plain"
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"a":1,"b":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		m.Handler(f1).ServeHTTP(w, r)
+
+		assert.Equal(t, "{OK:true Result:2}", w.Body.String())
+	})
+}
+
+func TestJSONRPC(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	h := m.JSONRPC()
+	h.Register("add", f1)
+	h.Register("noop", f2)
+	h.Register("fail", func(ctx context.Context) error {
+		return arpc.NewErrorCode("E1", "failed")
+	})
+	h.Register("internal", func() error {
+		return fmt.Errorf("db down")
+	})
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		w := call(`{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`)
+		assert.JSONEq(t, `{"jsonrpc":"2.0","result":3,"id":1}`, w.Body.String())
+	})
+
+	t.Run("Notification", func(t *testing.T) {
+		w := call(`{"jsonrpc":"2.0","method":"noop"}`)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"failed","data":{"code":"E1","message":"failed"}},"id":"x"}`,
+			call(`{"jsonrpc":"2.0","method":"fail","id":"x"}`).Body.String(),
+		)
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`,
+			call(`{"jsonrpc":"2.0","method":"internal","id":1}`).Body.String(),
+		)
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`,
+			call(`{"jsonrpc":"2.0","method":"unknown","id":1}`).Body.String(),
+		)
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+			call(`{"jsonrpc"`).Body.String(),
+		)
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+			call(`{"method":"add","id":1}`).Body.String(),
+		)
+		assert.Contains(t,
+			call(`{"jsonrpc":"2.0","method":"add","params":[1,2],"id":1}`).Body.String(),
+			`"code":-32602`,
+		)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		w := call(`[
+			{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1},
+			{"jsonrpc":"2.0","method":"noop"},
+			{"jsonrpc":"2.0","method":"noop","id":2},
+			1
+		]`)
+		assert.JSONEq(t, `[
+			{"jsonrpc":"2.0","result":3,"id":1},
+			{"jsonrpc":"2.0","result":{},"id":2},
+			{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}
+		]`, w.Body.String())
+	})
+
+	t.Run("EmptyBatch", func(t *testing.T) {
+		assert.JSONEq(t,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+			call(`[]`).Body.String(),
+		)
+	})
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	var (
+		mu      sync.Mutex
+		okCount int
+		errCnt  int
+	)
+	m.OnOK(func(w http.ResponseWriter, r *http.Request, req, res any) {
+		mu.Lock()
+		okCount++
+		mu.Unlock()
+	})
+	m.OnError(func(w http.ResponseWriter, r *http.Request, req any, err error) {
+		mu.Lock()
+		errCnt++
+		mu.Unlock()
+	})
+
+	mux := http.NewServeMux()
+	mt := m.Mounter(mux)
+	mt.BatchConcurrency = 2
+	mt.Mount("/add", f1)
+	mt.Mount("POST /noop", f2)
+	mt.Mount("/sse", f3)
+	mt.Mount("/fail", func() error {
+		return arpc.NewError("failed")
+	})
+	mux.Handle("/batch", mt.BatchHandler())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[
+		{"method":"/add","params":{"a":1,"b":2}},
+		{"method":"/noop"},
+		{"method":"/fail"},
+		{"method":"/sse"},
+		{"method":"/unknown"}
+	]`))
+	r.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"ok":true,"result":3},
+		{"ok":true,"result":{}},
+		{"ok":false,"error":{"message":"failed"}},
+		{"ok":false,"error":{"message":"method does not support batch"}},
+		{"ok":false,"error":{"message":"not found"}}
+	]`, w.Body.String())
+	assert.Equal(t, 2, okCount)
+	assert.Equal(t, 3, errCnt)
+}
+
+func TestBatchMethod(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	mux := http.NewServeMux()
+	mt := m.Mounter(mux)
+	mt.Mount("GET /items", func() (string, error) { return "get", nil })
+	mt.Mount("POST /items", func() (string, error) { return "post", nil })
+	mt.Mount("GET /only", func() (string, error) { return "get", nil })
+	mux.Handle("/batch", mt.BatchHandler())
+
+	assert.Panics(t, func() {
+		mt.Mount("POST  /items", func() {})
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[
+		{"method":"/items"},
+		{"method":"/only"}
+	]`))
+	r.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(w, r)
+
+	assert.JSONEq(t, `[
+		{"ok":true,"result":"post"},
+		{"ok":false,"error":{"message":"not found"}}
+	]`, w.Body.String())
+}
+
+func TestBatchPanic(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	mux := http.NewServeMux()
+	mt := m.Mounter(mux)
+	mt.Mount("/add", f1)
+	mt.Mount("/panic", func() error {
+		panic("boom")
+	})
+	mux.Handle("/batch", mt.BatchHandler())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[
+		{"method":"/panic"},
+		{"method":"/add","params":{"a":1,"b":2}}
+	]`))
+	r.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"ok":false,"error":{}},
+		{"ok":true,"result":3}
+	]`, w.Body.String())
+}
+
+func TestBatchLimit(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	mux := http.NewServeMux()
+	mt := m.Mounter(mux)
+	mt.BatchLimit = 1
+	mt.Mount("/add", f1)
+	mux.Handle("/batch", mt.BatchHandler())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/batch", strings.NewReader(`[
+		{"method":"/add","params":{"a":1,"b":2}},
+		{"method":"/add","params":{"a":1,"b":2}}
+	]`))
+	r.Header.Set("Content-Type", "application/json")
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"ok":false,"error":{"message":"too many calls in batch"}}`, w.Body.String())
+}
+
+type openAPIResult struct {
+	ID        int64          `json:"id"`
+	Name      *string        `json:"name"`
+	Tags      []string       `json:"tags,omitempty"`
+	Meta      map[string]int `json:"meta"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Parent    *openAPIResult `json:"parent,omitempty"`
+	Secret    string         `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	mux := http.NewServeMux()
+	mt := m.Mounter(mux)
+	mt.Mount("/add", f1)
+	mt.Mount("/sse", f3)
+	mt.Mount("GET /get", func(ctx context.Context, req *request) (*openAPIResult, error) {
+		return nil, nil
+	})
+	mt.Mount("GET /items", func(req *bindRequest) {})
+	mt.Mount("POST /items", func(req *bindRequest) {})
+	mux.Handle("/openapi.json", mt.OpenAPIHandler(arpc.OpenAPIInfo{Title: "test", Version: "1.0.0"}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/openapi.json", nil)
+	mux.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title string `json:"title"`
+		} `json:"info"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, "test", doc.Info.Title)
+	assert.Contains(t, doc.Paths["/add"], "post")
+	assert.Contains(t, doc.Paths["/get"], "get")
+	assert.Contains(t, string(doc.Paths["/sse"]["post"]), `"text/event-stream"`)
+	assert.Contains(t, string(doc.Paths["/add"]["post"]), `"result":{"format":"int64","type":"integer"}`)
+	assert.NotContains(t, string(doc.Paths["/get"]["get"]), `"requestBody"`)
+	if assert.Contains(t, doc.Paths["/items"], "get") && assert.Contains(t, doc.Paths["/items"], "post") {
+		var op struct {
+			OperationID string            `json:"operationId"`
+			Parameters  []json.RawMessage `json:"parameters"`
+			RequestBody json.RawMessage   `json:"requestBody"`
+		}
+		json.Unmarshal(doc.Paths["/items"]["get"], &op)
+		assert.Equal(t, "get_items", op.OperationID)
+		assert.Nil(t, op.RequestBody)
+		if assert.Len(t, op.Parameters, 8) {
+			assert.JSONEq(t, `{"name":"page","in":"query","schema":{"type":"integer","format":"int64"}}`, string(op.Parameters[0]))
+			assert.JSONEq(t, `{"name":"tag","in":"query","schema":{"type":"array","items":{"type":"string"}}}`, string(op.Parameters[5]))
+		}
+		assert.Contains(t, string(doc.Paths["/items"]["post"]), `"requestBody"`)
+	}
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"a": {"type": "integer", "format": "int64"},
+			"b": {"type": "integer", "format": "int64"}
+		},
+		"required": ["a", "b"]
+	}`, string(doc.Components.Schemas["request"]))
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer", "format": "int64"},
+			"name": {"type": "string"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"meta": {"type": "object", "additionalProperties": {"type": "integer", "format": "int64"}},
+			"createdAt": {"type": "string", "format": "date-time"},
+			"parent": {"$ref": "#/components/schemas/openAPIResult"}
+		},
+		"required": ["id", "meta", "createdAt"]
+	}`, string(doc.Components.Schemas["openAPIResult"]))
+}
+
+type validateRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+func (req *validateRequest) Valid() error {
+	var errs []error
+	if req.Name == "" {
+		errs = append(errs, arpc.NewFieldError("name", "required", "name required"))
+	}
+	if req.Email == "" {
+		errs = append(errs, arpc.NewFieldError("email", "required", "email required"))
+	}
+	if req.Age < 0 {
+		errs = append(errs, arpc.NewError("age must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func TestValidationError(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	h := m.Handler(func(req *validateRequest) {})
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Joined", func(t *testing.T) {
+		w := call(`{"age":-1}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"invalid fields",
+			"fields":[
+				{"field":"name","code":"required","message":"name required"},
+				{"field":"email","code":"required","message":"email required"},
+				{"field":"","message":"age must not be negative"}
+			]
+		}}`, w.Body.String())
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		w := call(`{"name":"a"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"email required",
+			"fields":[{"field":"email","code":"required","message":"email required"}]
+		}}`, w.Body.String())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		w := call(`{"name":"a","email":"a@example.com"}`)
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+	})
+}
+
+type tagItem struct {
+	Name string `json:"name" validate:"required,max=3"`
+}
+
+type tagRequest struct {
+	Name   string    `json:"name" validate:"required,min=2,max=5"`
+	Email  string    `json:"email" validate:"omitempty,email"`
+	Age    int       `json:"age" validate:"omitempty,min=1,max=100"`
+	Role   string    `json:"role" validate:"omitempty,oneof=admin user"`
+	Code   string    `json:"code" validate:"even"`
+	Items  []tagItem `json:"items" validate:"required"`
+	Parent *tagItem  `json:"parent"`
+	Agree  bool      `json:"agree"`
+	Opt    *string   `json:"opt" validate:"len=2"`
+}
+
+func (req *tagRequest) Valid() error {
+	if !req.Agree {
+		return arpc.NewFieldError("agree", "invalid", "must agree")
+	}
+	return nil
+}
+
+func TestValidateTags(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.RegisterRule("even", func(v reflect.Value, param string) error {
+		if len(v.String())%2 != 0 {
+			return fmt.Errorf("length must be even")
+		}
+		return nil
+	})
+	h := m.Handler(func(req *tagRequest) {})
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		w := call(`{"name":"a","email":"x","age":101,"role":"root","code":"abc","items":[{"name":"abcd"},{}],"parent":{},"opt":"abc"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"invalid fields",
+			"fields":[
+				{"field":"name","code":"min","message":"length must be at least 2"},
+				{"field":"email","code":"email","message":"invalid email"},
+				{"field":"age","code":"max","message":"must be at most 100"},
+				{"field":"role","code":"oneof","message":"must be one of admin, user"},
+				{"field":"code","code":"even","message":"length must be even"},
+				{"field":"items[0].name","code":"max","message":"length must be at most 3"},
+				{"field":"items[1].name","code":"required","message":"required"},
+				{"field":"parent.name","code":"required","message":"required"},
+				{"field":"opt","code":"len","message":"length must be 2"}
+			]
+		}}`, w.Body.String())
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		w := call(`{"agree":true}`)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"invalid fields",
+			"fields":[
+				{"field":"name","code":"required","message":"required"},
+				{"field":"items","code":"required","message":"required"}
+			]
+		}}`, w.Body.String())
+	})
+
+	t.Run("ValidAfterTags", func(t *testing.T) {
+		w := call(`{"name":"abc","items":[{"name":"a"}]}`)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"must agree",
+			"fields":[{"field":"agree","code":"invalid","message":"must agree"}]
+		}}`, w.Body.String())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		w := call(`{"name":"abc","email":"a@example.com","age":20,"role":"user","code":"ab","items":[{"name":"a"}],"agree":true,"opt":"ab"}`)
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, w.Body.String())
+	})
+}
+
+func TestValidateTagsUnknownRule(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		Name string `json:"name" validate:"required,gte=1,max=3" arpc:"min=2"`
+	}
+
+	call := func(m *arpc.Manager, body string) *httptest.ResponseRecorder {
+		h := m.Handler(func(req *request) {})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		m := arpc.New()
+		w := call(m, `{"name":"abcd"}`)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"length must be at most 3",
+			"fields":[{"field":"name","code":"max","message":"length must be at most 3"}]
+		}}`, w.Body.String())
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		m := arpc.New()
+		assert.JSONEq(t, `{"ok":true,"result":{}}`, call(m, `{"name":"a"}`).Body.String())
+
+		m.RegisterRule("gte", ruleMinLen)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"length must be at least 2",
+			"fields":[{"field":"name","code":"gte","message":"length must be at least 2"}]
+		}}`, call(m, `{"name":"a"}`).Body.String())
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		m := arpc.New()
+		m.ValidateTag = "arpc"
+		w := call(m, `{"name":"a"}`)
+		assert.JSONEq(t, `{"ok":false,"error":{
+			"code":"validation",
+			"message":"length must be at least 2",
+			"fields":[{"field":"name","code":"min","message":"length must be at least 2"}]
+		}}`, w.Body.String())
+	})
+}
+
+func TestValidateTagsZero(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		Age   int     `json:"age" validate:"min=18"`
+		Score *int    `json:"score" validate:"min=1"`
+		Tags  []int64 `json:"tags" validate:"min=1"`
+	}
+
+	m := arpc.New()
+	h := m.Handler(func(req *request) {})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"age":0}`))
+	r.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(w, r)
+
+	assert.JSONEq(t, `{"ok":false,"error":{
+		"code":"validation",
+		"message":"invalid fields",
+		"fields":[
+			{"field":"age","code":"min","message":"must be at least 18"},
+			{"field":"tags","code":"min","message":"length must be at least 1"}
+		]
+	}}`, w.Body.String())
+}
+
+func TestValidateTagsInvalidRule(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+
+	t.Run("Param", func(t *testing.T) {
+		type request struct {
+			Age int `json:"age" validate:"min=abc"`
+		}
+		assert.Panics(t, func() {
+			m.Handler(func(req *request) {})
+		})
+	})
+
+	t.Run("Type", func(t *testing.T) {
+		type item struct {
+			ID int `json:"id" validate:"email"`
+		}
+		type request struct {
+			Items []item `json:"items"`
+		}
+		assert.Panics(t, func() {
+			arpc.HandleNoResult(m, func(ctx context.Context, req *request) error { return nil })
+		})
+	})
+}
+
+func ruleMinLen(v reflect.Value, param string) error {
+	if v.Len() < 2 {
+		return fmt.Errorf("length must be at least 2")
+	}
+	return nil
+}
+
+type usernameRequest struct {
+	Username string `json:"username"`
+}
+
+func (req *usernameRequest) Valid(ctx context.Context) error {
+	if req.Username == ctx.Value("user") {
+		return arpc.NewFieldError("username", "taken", "username already taken")
+	}
+	return nil
+}
+
+func TestContextValidatable(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.OnValidate(func(ctx context.Context, req any) error {
+		if req, ok := req.(*usernameRequest); ok && req.Username == "admin" {
+			return arpc.NewError("reserved username")
+		}
+		return nil
+	})
+	h := m.Middleware(func(ctx *arpc.MiddlewareContext) error {
+		ctx.SetRequestContext(context.WithValue(ctx, "user", "taken"))
+		return nil
+	})(m.Handler(func(req *usernameRequest) {}))
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.JSONEq(t, `{"ok":false,"error":{
+		"code":"validation",
+		"message":"username already taken",
+		"fields":[{"field":"username","code":"taken","message":"username already taken"}]
+	}}`, call(`{"username":"taken"}`).Body.String())
+	assert.JSONEq(t, `{"ok":false,"error":{"message":"reserved username"}}`, call(`{"username":"admin"}`).Body.String())
+	assert.JSONEq(t, `{"ok":true,"result":{}}`, call(`{"username":"free"}`).Body.String())
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	newManager := func(hooked *error) *arpc.Manager {
+		m := arpc.New()
+		m.Recover = true
+		m.OnError(func(w http.ResponseWriter, r *http.Request, req any, err error) {
+			*hooked = err
+		})
+		return m
+	}
+
+	t.Run("Hijack", func(t *testing.T) {
+		var hooked error
+		m := newManager(&hooked)
+		srv := httptest.NewServer(m.Handler(func(w http.ResponseWriter) {
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			buf.Flush()
+		}))
+		defer srv.Close()
+
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "hijacked", string(body))
+		assert.NoError(t, hooked)
+	})
+
+	t.Run("Handler", func(t *testing.T) {
+		var hooked error
+		m := newManager(&hooked)
+		h := m.Handler(func(req *request) int {
+			panic("boom")
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+		r.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"ok":false,"error":{}}`, w.Body.String())
+
+		var perr *arpc.PanicError
+		if assert.True(t, errors.As(hooked, &perr)) {
+			assert.Equal(t, "boom", perr.Value)
+			assert.Contains(t, string(perr.Stack), "TestRecover")
+		}
+	})
+
+	t.Run("Handle", func(t *testing.T) {
+		var hooked error
+		m := newManager(&hooked)
+		h := arpc.HandleNoRequest(m, func(ctx context.Context) (*request, error) {
+			panic(fmt.Errorf("boom"))
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.IsType(t, &arpc.PanicError{}, hooked)
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		var hooked error
+		m := newManager(&hooked)
+		h := m.Middleware(func(ctx *arpc.MiddlewareContext) error {
+			panic("boom")
+		})(m.Handler(f2))
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.IsType(t, &arpc.PanicError{}, hooked)
+	})
+
+	t.Run("AfterWrite", func(t *testing.T) {
+		var hooked error
+		m := newManager(&hooked)
+		h := m.Handler(func(w arpc.SSEResponseWriter) {
+			w.WriteData("1")
+			panic("boom")
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			h.ServeHTTP(w, r)
+		})
+		assert.Equal(t, "data: 1\n\n", w.Body.String())
+		assert.IsType(t, &arpc.PanicError{}, hooked)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		h := arpc.New().Handler(func() {
+			panic("boom")
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		assert.PanicsWithValue(t, "boom", func() {
+			h.ServeHTTP(w, r)
+		})
+	})
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	fsys := fstest.MapFS{
+		"report.csv": {Data: []byte("a,b\n1,2\n")},
+	}
+
+	t.Run("File", func(t *testing.T) {
+		h := m.Handler(func() (fs.File, error) {
+			return fsys.Open("report.csv")
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Range", "bytes=4-")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=report.csv`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "1,2\n", w.Body.String())
+	})
+
+	t.Run("OSFile", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "data.json")
+		os.WriteFile(name, []byte(`{"a":1}`), 0644)
+
+		h := m.Handler(func() (*os.File, error) {
+			return os.Open(name)
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, "7", w.Header().Get("Content-Length"))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		h := m.Handler(func() (arpc.StreamResult, error) {
+			return arpc.NewStream("text/plain", "", -1, io.MultiReader(strings.NewReader("hello"))), nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		h := m.Handler(func() (arpc.StreamResult, error) {
+			return arpc.NewStream("text/csv", "export.csv", 10, errReader{}), nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+		assert.JSONEq(t, `{"ok":false,"error":{}}`, w.Body.String())
+	})
+
+	t.Run("ErrorHeaders", func(t *testing.T) {
+		m := arpc.New()
+		m.OnBeforeWrite(func(w http.ResponseWriter, r *http.Request, req, res any) {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		})
+		h := m.Handler(func() (*statusStream, error) {
+			return &statusStream{arpc.NewStream("text/csv", "", -1, errReader{}), http.StatusCreated}, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Set-Cookie"))
+		assert.Empty(t, w.Header().Get("X-Export"))
+		assert.JSONEq(t, `{"ok":false,"error":{}}`, w.Body.String())
+	})
+
+	t.Run("SeekableStatus", func(t *testing.T) {
+		h := m.Handler(func() (*statusStream, error) {
+			return &statusStream{arpc.NewStream("text/plain", "", 5, strings.NewReader("hello")), http.StatusCreated}, nil
+		})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Range", "bytes=1-")
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "1", w.Header().Get("X-Export"))
+		assert.Equal(t, "hello", w.Body.String())
+	})
+}
+
+type statusStream struct {
+	arpc.StreamResult
+	status int
+}
+
+func (s *statusStream) ResponseStatus() int { return s.status }
+
+func (s *statusStream) ResponseHeaders() http.Header {
+	return http.Header{"X-Export": {"1"}}
+}
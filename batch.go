@@ -0,0 +1,167 @@
+package arpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sync"
+)
+
+type batchCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// DefaultBatchLimit is the max calls in a batch when Mounter.BatchLimit is zero
+const DefaultBatchLimit = 100
+
+var (
+	errBatchUnsupported = NewProtocolError("", "method does not support batch")
+	errBatchTooLarge    = NewProtocolError("", "too many calls in batch")
+)
+
+// BatchHandler returns handler that calls many mounted functions in one request,
+// the request is an array of {"method":"/pattern","params":{...}},
+// and the response is an array of the response of each call,
+// each call is served as POST request to the mounted path
+func (m *Mounter) BatchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var calls []batchCall
+		err := json.NewDecoder(r.Body).Decode(&calls)
+		if err != nil {
+			m.Manager.encodeAndHookError(w, r, nil, WrapError(err))
+			return
+		}
+		if len(calls) > m.batchLimit() {
+			m.Manager.encodeAndHookError(w, r, nil, errBatchTooLarge)
+			return
+		}
+
+		results := make([][]byte, len(calls))
+
+		var (
+			wg  sync.WaitGroup
+			sem chan struct{}
+		)
+		if m.BatchConcurrency > 0 {
+			sem = make(chan struct{}, m.BatchConcurrency)
+		}
+		for i, call := range calls {
+			wg.Add(1)
+			if sem != nil {
+				sem <- struct{}{}
+			}
+			go func() {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				results[i] = m.batchCall(r, call)
+			}()
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("["))
+		for i, res := range results {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write(res)
+		}
+		w.Write([]byte("]\n"))
+	})
+}
+
+func (m *Mounter) batchLimit() int {
+	if m.BatchLimit <= 0 {
+		return DefaultBatchLimit
+	}
+	return m.BatchLimit
+}
+
+// batchCall calls mounted handler with sub request, returns the response body
+func (m *Mounter) batchCall(r *http.Request, call batchCall) []byte {
+	w := &batchResponseWriter{header: make(http.Header)}
+	m.serveBatchCall(w, r, call)
+
+	b := bytes.TrimSpace(w.buf.Bytes())
+	if !json.Valid(b) {
+		return jsonNull
+	}
+	return b
+}
+
+// batchHandler returns handler that serves POST request to path,
+// handler mounted with other method can not be called in batch
+func (m *Mounter) batchHandler(path string) *mountedHandler {
+	if mh := m.handlers[http.MethodPost+" "+path]; mh != nil {
+		return mh
+	}
+	return m.handlers[path]
+}
+
+// serveBatchCall serves a batch call,
+// panic is recovered into the call's internal error since the call runs in its own goroutine
+func (m *Mounter) serveBatchCall(w *batchResponseWriter, r *http.Request, call batchCall) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		w.buf.Reset()
+		m.Manager.encodeAndHookError(w, r, nil, &PanicError{Value: p, Stack: debug.Stack()})
+	}()
+
+	mh := m.batchHandler(call.Method)
+	switch {
+	case mh == nil:
+		m.Manager.encodeAndHookError(w, r, nil, ErrNotFound)
+	case mh.fn.hasWriter, mh.fn.isStream, mh.fn.isChan:
+		m.Manager.encodeAndHookError(w, r, nil, errBatchUnsupported)
+	default:
+		mh.h.ServeHTTP(w, newBatchRequest(r, call))
+	}
+}
+
+// newBatchRequest creates sub request for a batch call from the batch request
+func newBatchRequest(r *http.Request, call batchCall) *http.Request {
+	params := call.Params
+	if len(params) == 0 {
+		params = jsonNull
+	}
+
+	nr := r.Clone(r.Context())
+	nr.Method = http.MethodPost
+	nr.URL = &url.URL{Path: call.Method}
+	nr.RequestURI = call.Method
+	nr.Body = io.NopCloser(bytes.NewReader(params))
+	nr.ContentLength = int64(len(params))
+	nr.Header.Set("Content-Type", mediaTypeJSON)
+	nr.Header.Set("Accept", mediaTypeJSON)
+	nr.Header.Del("Content-Length")
+	nr.Form = nil
+	nr.PostForm = nil
+	nr.MultipartForm = nil
+	return nr
+}
+
+// batchResponseWriter records response body of a batch call
+type batchResponseWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) WriteHeader(statusCode int) {}
+
+func (w *batchResponseWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
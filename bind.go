@@ -0,0 +1,243 @@
+package arpc
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	tagForm  = "form"
+	tagQuery = "query"
+)
+
+type bindField struct {
+	index []int
+	name  string
+	query bool // read from query instead of form
+}
+
+var (
+	bindFieldsCache sync.Map // map[reflect.Type][]bindField
+
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// bindFields returns all fields in struct t that tagged with form or query tag
+func bindFields(t reflect.Type) []bindField {
+	if fs, ok := bindFieldsCache.Load(t); ok {
+		return fs.([]bindField)
+	}
+
+	fs := collectBindFields(t, map[reflect.Type]bool{})
+	bindFieldsCache.Store(t, fs)
+	return fs
+}
+
+// collectBindFields collects bind fields in t,
+// unexported fields are skipped like encoding/json,
+// visited guards embedded struct cycles
+func collectBindFields(t reflect.Type, visited map[reflect.Type]bool) []bindField {
+	if visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var fs []bindField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		// embedded struct without tag
+		if sf.Anonymous && sf.Tag.Get(tagQuery) == "" && sf.Tag.Get(tagForm) == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				// can not allocate unexported embedded pointer
+				if !sf.IsExported() {
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if ft.Kind() != reflect.Struct {
+				continue
+			}
+			for _, f := range collectBindFields(ft, visited) {
+				f.index = append([]int{i}, f.index...)
+				fs = append(fs, f)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name, ok := sf.Tag.Lookup(tagQuery); ok {
+			if name != "-" {
+				fs = append(fs, bindField{index: sf.Index, name: name, query: true})
+			}
+			continue
+		}
+		if name, ok := sf.Tag.Lookup(tagForm); ok {
+			if name != "-" {
+				fs = append(fs, bindField{index: sf.Index, name: name})
+			}
+			continue
+		}
+	}
+	return fs
+}
+
+// bindable returns true if v is a pointer to struct that has any form or query tag
+func bindable(v any) bool {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	return len(bindFields(t.Elem())) > 0
+}
+
+// bindValues fills v's fields from form and query values using struct tags
+func bindValues(v any, form, query url.Values) error {
+	rv := reflect.ValueOf(v).Elem()
+	for _, f := range bindFields(rv.Type()) {
+		src := form
+		if f.query {
+			src = query
+		}
+		vs, ok := src[f.name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+
+		fv := fieldByIndexAlloc(rv, f.index)
+		err := setValues(fv, vs)
+		if err != nil {
+			return &ProtocolError{Message: fmt.Sprintf("invalid value for field %q: %v", f.name, err)}
+		}
+	}
+	return nil
+}
+
+// fieldByIndexAlloc likes reflect.Value.FieldByIndex but allocates nil embedded pointers
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func setValues(v reflect.Value, vs []string) error {
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerType) && !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+		for i, x := range vs {
+			err := setValue(s.Index(i), x)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, vs[0])
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+
+	if v.Type() == timeType {
+		if s == "" {
+			return nil
+		}
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	// empty value leaves the field as zero value
+	if s == "" {
+		return nil
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid unsigned integer", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid number", s)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a valid time", s)
+}
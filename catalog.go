@@ -0,0 +1,244 @@
+package arpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog is the registry of error codes with localized messages
+type Catalog struct {
+	mu    sync.RWMutex
+	codes map[string]*ErrorCode
+}
+
+// NewCatalog creates new error code catalog
+func NewCatalog() *Catalog {
+	return &Catalog{codes: make(map[string]*ErrorCode)}
+}
+
+// Register registers error code with default message template,
+// template parameters are written as {name}, i.e. "user {id} not found"
+func (c *Catalog) Register(code, message string) *ErrorCode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.codes[code]; ok {
+		panic("arpc: error code " + code + " already registered")
+	}
+	ec := &ErrorCode{
+		catalog:  c,
+		code:     code,
+		message:  message,
+		messages: make(map[string]string),
+	}
+	c.codes[code] = ec
+	return ec
+}
+
+// Lookup returns registered error code
+func (c *Catalog) Lookup(code string) (*ErrorCode, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ec, ok := c.codes[code]
+	return ec, ok
+}
+
+// MarshalJSON implements json.Marshaler,
+// exports all codes with default message and locale templates
+func (c *Catalog) MarshalJSON() ([]byte, error) {
+	type entry struct {
+		Message string            `json:"message"`
+		Locales map[string]string `json:"locales,omitempty"`
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	codes := make(map[string]entry, len(c.codes))
+	for code, ec := range c.codes {
+		e := entry{Message: ec.message}
+		if len(ec.messages) > 0 {
+			e.Locales = make(map[string]string, len(ec.messages))
+			for l, msg := range ec.messages {
+				e.Locales[l] = msg
+			}
+		}
+		codes[code] = e
+	}
+	return json.Marshal(codes)
+}
+
+// Handler returns http handler that serves catalog as JSON
+func (c *Catalog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType(mediaTypeJSON))
+		json.NewEncoder(w).Encode(c)
+	})
+}
+
+// ErrorCode is the error code registered in Catalog
+type ErrorCode struct {
+	catalog  *Catalog
+	code     string
+	message  string
+	messages map[string]string // locale => template
+}
+
+// Locale adds message template for locale i.e. "th", "en-US"
+func (ec *ErrorCode) Locale(locale, message string) *ErrorCode {
+	ec.catalog.mu.Lock()
+	defer ec.catalog.mu.Unlock()
+
+	ec.messages[strings.ToLower(locale)] = message
+	return ec
+}
+
+// Code returns error code
+func (ec *ErrorCode) Code() string {
+	return ec.code
+}
+
+// New creates new Error from error code with template parameters,
+// the message renders in request locale when encode
+func (ec *ErrorCode) New(params map[string]any) error {
+	return &Error{
+		code:   ec.code,
+		msg:    renderMessage(ec.message, params),
+		ec:     ec,
+		params: params,
+	}
+}
+
+// Message renders message for locale, fallbacks to default message
+func (ec *ErrorCode) Message(locale string, params map[string]any) string {
+	ec.catalog.mu.RLock()
+	defer ec.catalog.mu.RUnlock()
+
+	return renderMessage(ec.template(locale), params)
+}
+
+func (ec *ErrorCode) template(locale string) string {
+	locale = strings.ToLower(locale)
+	if msg, ok := ec.messages[locale]; ok {
+		return msg
+	}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		if msg, ok := ec.messages[base]; ok {
+			return msg
+		}
+	}
+	return ec.message
+}
+
+func (ec *ErrorCode) locales() []string {
+	ec.catalog.mu.RLock()
+	defer ec.catalog.mu.RUnlock()
+
+	ls := make([]string, 0, len(ec.messages))
+	for l := range ec.messages {
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+func renderMessage(tmpl string, params map[string]any) string {
+	if len(params) == 0 {
+		return tmpl
+	}
+	oldnew := make([]string, 0, len(params)*2)
+	for k, v := range params {
+		oldnew = append(oldnew, "{"+k+"}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(oldnew...).Replace(tmpl)
+}
+
+type localeKey struct{}
+
+// WithLocale returns new context with locale,
+// locale from context takes precedence over Accept-Language header
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns locale from context
+func Locale(ctx context.Context) string {
+	s, _ := ctx.Value(localeKey{}).(string)
+	return s
+}
+
+// localizeError renders catalog error message in request locale
+func localizeError(r *http.Request, err error) error {
+	var appErr *Error
+	if !errors.As(err, &appErr) || appErr.ec == nil {
+		return err
+	}
+
+	locale := Locale(r.Context())
+	if locale == "" {
+		locale = matchLocale(r.Header.Get("Accept-Language"), appErr.ec.locales())
+	}
+	if locale == "" {
+		return err
+	}
+	e := *appErr
+	e.msg = appErr.ec.Message(locale, appErr.params)
+	return &e
+}
+
+// matchLocale returns the best locale from Accept-Language header
+func matchLocale(accept string, locales []string) string {
+	if accept == "" || len(locales) == 0 {
+		return ""
+	}
+
+	type langRange struct {
+		tag string
+		q   float64
+	}
+	var ranges []langRange
+	for _, x := range strings.Split(accept, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(x), ";")
+		q := 1.0
+		if s, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		ranges = append(ranges, langRange{strings.ToLower(tag), q})
+	}
+	slices.SortStableFunc(ranges, func(a, b langRange) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	for _, x := range ranges {
+		if x.tag == "*" {
+			return ""
+		}
+		if slices.Contains(locales, x.tag) {
+			return x.tag
+		}
+		if base, _, ok := strings.Cut(x.tag, "-"); ok && slices.Contains(locales, base) {
+			return base
+		}
+	}
+	return ""
+}
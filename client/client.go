@@ -0,0 +1,166 @@
+// Package client calls arpc services
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/acoshift/arpc/v2"
+)
+
+// Client is the arpc client
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client // http.DefaultClient when nil
+	Header     http.Header  // headers send with every request
+
+	// PrepareRequest calls before send each request, i.e. inject authorization header
+	PrepareRequest func(r *http.Request) error
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// InternalError is the error when server returns internal error
+type InternalError struct {
+	StatusCode int
+	ID         string // error id when server generates ErrorID
+}
+
+func (err *InternalError) Error() string {
+	if err.ID != "" {
+		return "arpc: internal error " + err.ID
+	}
+	return "arpc: internal error"
+}
+
+// StatusError is the error when server returns non arpc response
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (err *StatusError) Error() string {
+	return "arpc: unexpected response status " + err.Status
+}
+
+// Call calls arpc function at path with req, then decodes result into Res
+func Call[Req, Res any](ctx context.Context, c *Client, path string, req *Req) (*Res, error) {
+	var res Res
+	err := c.Do(ctx, path, req, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Do calls arpc function at path with req, then decodes result into res,
+// error response returns as *arpc.Error, *arpc.ValidationError, *arpc.ProtocolError or *InternalError,
+// application/problem+json error response also supported,
+// error details decodes as json.RawMessage
+func (c *Client) Do(ctx context.Context, path string, req, res any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range c.Header {
+		r.Header[k] = append([]string(nil), vs...)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	if c.PrepareRequest != nil {
+		err = c.PrepareRequest(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient().Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/problem+json" {
+		return decodeProblem(resp)
+	}
+
+	var env struct {
+		OK     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+		Error  struct {
+			Code    string             `json:"code"`
+			Message string             `json:"message"`
+			Fields  []*arpc.FieldError `json:"fields"`
+			Details json.RawMessage    `json:"details"`
+			ID      string             `json:"id"`
+		} `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&env)
+	if err != nil {
+		if resp.StatusCode == http.StatusInternalServerError {
+			return &InternalError{StatusCode: resp.StatusCode, ID: resp.Header.Get("X-Error-Id")}
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+			return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+		return fmt.Errorf("arpc: decode response; %w", err)
+	}
+
+	switch {
+	case env.OK:
+		if res == nil || len(env.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(env.Result, res)
+	case resp.StatusCode == http.StatusOK && env.Error.Code == arpc.ValidationErrorCode && env.Error.Fields != nil:
+		return arpc.NewValidationError(env.Error.Fields...)
+	case resp.StatusCode == http.StatusOK && env.Error.Details != nil:
+		return arpc.NewErrorDetails(env.Error.Code, env.Error.Message, env.Error.Details)
+	case resp.StatusCode == http.StatusOK:
+		return arpc.NewErrorCode(env.Error.Code, env.Error.Message)
+	case resp.StatusCode == http.StatusBadRequest:
+		return &arpc.ProtocolError{Code: env.Error.Code, Message: env.Error.Message}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		id := env.Error.ID
+		if id == "" {
+			id = resp.Header.Get("X-Error-Id")
+		}
+		return &InternalError{StatusCode: resp.StatusCode, ID: id}
+	default:
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+}
+
+func decodeProblem(resp *http.Response) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	p, err := arpc.ParseProblem(data)
+	if err != nil {
+		return fmt.Errorf("arpc: decode problem; %w", err)
+	}
+	if p.Status == 0 {
+		p.Status = resp.StatusCode
+	}
+	if p.Status >= http.StatusInternalServerError {
+		return &InternalError{StatusCode: resp.StatusCode, ID: p.ID}
+	}
+	return p.Err()
+}
@@ -0,0 +1,198 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/acoshift/arpc/v2"
+	"github.com/acoshift/arpc/v2/client"
+)
+
+type addRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type addResult struct {
+	Sum int `json:"sum"`
+}
+
+type validateRequest struct{}
+
+func (req *validateRequest) Valid() error {
+	return errors.Join(
+		arpc.NewFieldError("name", "required", "name required"),
+		arpc.NewFieldError("email", "email", "invalid email"),
+	)
+}
+
+func TestCall(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	mux := http.NewServeMux()
+	mux.Handle("/add", m.Handler(func(ctx context.Context, req *addRequest) (*addResult, error) {
+		if req.A < 0 {
+			return nil, arpc.NewErrorCode("NEGATIVE", "a must not be negative")
+		}
+		return &addResult{Sum: req.A + req.B}, nil
+	}))
+	mux.Handle("/validate", m.Handler(func(req *validateRequest) {}))
+	mux.Handle("/fail", m.Handler(func() error {
+		return fmt.Errorf("db down")
+	}))
+	mux.Handle("/quota", m.Handler(func() error {
+		return arpc.NewErrorDetails("QUOTA", "quota exceeded", map[string]int{"remaining": 0})
+	}))
+	mux.Handle("/auth", m.Handler(func(r *http.Request) (string, error) {
+		return r.Header.Get("Authorization") + " " + r.Header.Get("X-Client"), nil
+	}))
+	mux.Handle("/", m.NotFoundHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &client.Client{
+		BaseURL: srv.URL,
+		Header:  http.Header{"X-Client": {"test"}},
+		PrepareRequest: func(r *http.Request) error {
+			r.Header.Set("Authorization", "Bearer token")
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		res, err := client.Call[addRequest, addResult](ctx, c, "/add", &addRequest{A: 1, B: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, res.Sum)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		var res string
+		err := c.Do(ctx, "/auth", struct{}{}, &res)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer token test", res)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := client.Call[addRequest, addResult](ctx, c, "/add", &addRequest{A: -1})
+		var e *arpc.Error
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "NEGATIVE", e.Code())
+			assert.Equal(t, "a must not be negative", e.Message())
+		}
+	})
+
+	t.Run("Details", func(t *testing.T) {
+		err := c.Do(ctx, "/quota", struct{}{}, nil)
+		var e *arpc.Error
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "QUOTA", e.Code())
+			assert.JSONEq(t, `{"remaining":0}`, string(e.Details().(json.RawMessage)))
+		}
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		err := c.Do(ctx, "/validate", struct{}{}, nil)
+		var e *arpc.ValidationError
+		if assert.True(t, errors.As(err, &e)) && assert.Len(t, e.Fields, 2) {
+			assert.Equal(t, "email", e.Fields[1].Field)
+			assert.Equal(t, "invalid email", e.Fields[1].Message)
+		}
+	})
+
+	t.Run("ProtocolError", func(t *testing.T) {
+		_, err := client.Call[addRequest, addResult](ctx, c, "/unknown", &addRequest{})
+		var e *arpc.ProtocolError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "not found", e.Message)
+		}
+	})
+
+	t.Run("InternalError", func(t *testing.T) {
+		err := c.Do(ctx, "/fail", struct{}{}, nil)
+		var e *client.InternalError
+		assert.True(t, errors.As(err, &e))
+	})
+}
+
+func TestCallProblem(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.ErrorEncoder = m.ProblemEncoder(http.StatusUnprocessableEntity)
+	m.ErrorID = func() string { return "abc" }
+	mux := http.NewServeMux()
+	mux.Handle("/add", m.Handler(func(ctx context.Context, req *addRequest) (*addResult, error) {
+		return nil, arpc.NewErrorCode("NEGATIVE", "a must not be negative")
+	}))
+	mux.Handle("/validate", m.Handler(func(req *validateRequest) {}))
+	mux.Handle("/fail", m.Handler(func() error {
+		return fmt.Errorf("db down")
+	}))
+	mux.Handle("/", m.NotFoundHandler())
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &client.Client{BaseURL: srv.URL}
+	ctx := context.Background()
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := client.Call[addRequest, addResult](ctx, c, "/add", &addRequest{A: -1})
+		var e *arpc.Error
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "NEGATIVE", e.Code())
+			assert.Equal(t, "a must not be negative", e.Message())
+		}
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		err := c.Do(ctx, "/validate", struct{}{}, nil)
+		var e *arpc.ValidationError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Len(t, e.Fields, 2)
+		}
+	})
+
+	t.Run("ProtocolError", func(t *testing.T) {
+		err := c.Do(ctx, "/unknown", struct{}{}, nil)
+		var e *arpc.ProtocolError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, "not found", e.Message)
+		}
+	})
+
+	t.Run("InternalError", func(t *testing.T) {
+		err := c.Do(ctx, "/fail", struct{}{}, nil)
+		var e *client.InternalError
+		if assert.True(t, errors.As(err, &e)) {
+			assert.Equal(t, http.StatusInternalServerError, e.StatusCode)
+			assert.Equal(t, "abc", e.ID)
+		}
+	})
+}
+
+func TestCallErrorID(t *testing.T) {
+	t.Parallel()
+
+	m := arpc.New()
+	m.ErrorID = arpc.NewErrorID
+	srv := httptest.NewServer(m.Handler(func() error {
+		return fmt.Errorf("db down")
+	}))
+	defer srv.Close()
+
+	c := &client.Client{BaseURL: srv.URL}
+	err := c.Do(context.Background(), "/", struct{}{}, nil)
+	var e *client.InternalError
+	if assert.True(t, errors.As(err, &e)) {
+		assert.Len(t, e.ID, 32)
+	}
+}
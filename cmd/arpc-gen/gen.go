@@ -0,0 +1,635 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"log"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const arpcPkgPath = "github.com/acoshift/arpc/v2"
+
+type endpoint struct {
+	name   string
+	method string
+	path   string
+	req    types.Type // nil if function does not take request
+	res    types.Type // nil if function does not return result
+	sse    bool
+}
+
+type generator struct {
+	endpoints []*endpoint
+	decls     []string          // declared types in order
+	names     map[string]string // type string => ts name
+	used      map[string]bool   // used ts names
+	code      map[string]string // ts name => declaration
+}
+
+func newGenerator() *generator {
+	return &generator{
+		names: make(map[string]string),
+		used:  make(map[string]bool),
+		code:  make(map[string]string),
+	}
+}
+
+// findEndpoints finds all mounted arpc handlers in the package
+func (g *generator) findEndpoints(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			pattern, f := findHandler(pkg.TypesInfo, call)
+			if pattern == nil || f == nil {
+				return true
+			}
+
+			tv := pkg.TypesInfo.Types[pattern]
+			if tv.Value == nil || tv.Value.Kind() != constant.String {
+				log.Printf("%s: skip non-constant pattern", pkg.Fset.Position(pattern.Pos()))
+				return true
+			}
+			sig, ok := pkg.TypesInfo.TypeOf(f).Underlying().(*types.Signature)
+			if !ok {
+				return true
+			}
+
+			ep := newEndpoint(constant.StringVal(tv.Value), sig)
+			if ep == nil {
+				log.Printf("%s: skip handler that uses http.ResponseWriter", pkg.Fset.Position(f.Pos()))
+				return true
+			}
+			g.endpoints = append(g.endpoints, ep)
+			return true
+		})
+	}
+}
+
+// findHandler returns pattern and function expressions if call mounts arpc handler
+func findHandler(info *types.Info, call *ast.CallExpr) (pattern, f ast.Expr) {
+	fn, _ := typeutil.Callee(info, call).(*types.Func)
+	if fn == nil {
+		return nil, nil
+	}
+
+	if isArpc(fn) && fn.Name() == "Mount" {
+		switch recvName(fn) {
+		case "Manager":
+			if len(call.Args) == 3 {
+				return call.Args[1], call.Args[2]
+			}
+		case "Mounter":
+			if len(call.Args) == 2 {
+				return call.Args[0], call.Args[1]
+			}
+		}
+		return nil, nil
+	}
+
+	// mux.Handle(pattern, m.Handler(f)) or mux.Handle(pattern, arpc.Handle(m, f))
+	if fn.Name() != "Handle" || len(call.Args) != 2 {
+		return nil, nil
+	}
+	inner, ok := ast.Unparen(call.Args[1]).(*ast.CallExpr)
+	if !ok {
+		return nil, nil
+	}
+	innerFn, _ := typeutil.Callee(info, inner).(*types.Func)
+	if innerFn == nil || !isArpc(innerFn) {
+		return nil, nil
+	}
+	switch {
+	case recvName(innerFn) == "Manager" && innerFn.Name() == "Handler" && len(inner.Args) == 1:
+		return call.Args[0], inner.Args[0]
+	case recvName(innerFn) == "" && strings.HasPrefix(innerFn.Name(), "Handle") && len(inner.Args) == 2:
+		return call.Args[0], inner.Args[1]
+	}
+	return nil, nil
+}
+
+func isArpc(fn *types.Func) bool {
+	return fn.Pkg() != nil && fn.Pkg().Path() == arpcPkgPath
+}
+
+func recvName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return ""
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if n, ok := t.(*types.Named); ok {
+		return n.Obj().Name()
+	}
+	return ""
+}
+
+// newEndpoint creates endpoint from function signature using arpc signature rules,
+// returns nil if function writes response by itself
+func newEndpoint(pattern string, sig *types.Signature) *endpoint {
+	ep := endpoint{
+		method: "POST",
+		path:   pattern,
+	}
+	if i := strings.LastIndexByte(pattern, ' '); i >= 0 {
+		ep.method = pattern[:i]
+		ep.path = pattern[i+1:]
+	}
+	ep.name = funcName(ep.path)
+
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		t := params.At(i).Type()
+		// grpc call options
+		if i == params.Len()-1 && sig.Variadic() {
+			break
+		}
+
+		if isSendFunc(t) {
+			ep.sse = true
+			continue
+		}
+
+		switch types.TypeString(t, nil) {
+		case "context.Context", "*net/http.Request":
+		case "net/http.ResponseWriter":
+			return nil
+		case arpcPkgPath + ".SSEResponseWriter":
+			ep.sse = true
+		default:
+			ep.req = t
+		}
+	}
+
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		t := results.At(i).Type()
+		if types.TypeString(t, nil) == "error" {
+			continue
+		}
+		if ch, ok := t.Underlying().(*types.Chan); ok && ch.Dir() != types.SendOnly {
+			ep.sse = true
+			continue
+		}
+		ep.res = t
+	}
+	return &ep
+}
+
+// query returns true if request is sent as query,
+// arpc decodes GET and HEAD request from query, EventSource always sends GET
+func (ep *endpoint) query() bool {
+	return ep.sse || ep.method == "GET" || ep.method == "HEAD"
+}
+
+// isSendFunc returns true if t is sse send function func(T) error
+func isSendFunc(t types.Type) bool {
+	sig, ok := t.Underlying().(*types.Signature)
+	return ok && sig.Params().Len() == 1 && sig.Results().Len() == 1 &&
+		types.TypeString(sig.Results().At(0).Type(), nil) == "error"
+}
+
+// funcName converts path to camel case function name, i.e. "/user.get" to "userGet"
+func funcName(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteByte('_')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "index"
+	}
+	return b.String()
+}
+
+// tsType returns TypeScript type for t, declares named types when needed
+func (g *generator) tsType(t types.Type) string {
+	t = types.Unalias(t)
+
+	switch t := t.(type) {
+	case *types.Pointer:
+		return g.tsType(t.Elem()) + " | null"
+	case *types.Named:
+		return g.named(t)
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return "boolean"
+		case t.Info()&types.IsNumeric != 0:
+			return "number"
+		case t.Info()&types.IsString != 0:
+			return "string"
+		}
+	case *types.Slice:
+		if isByte(t.Elem()) {
+			return "string"
+		}
+		return arrayOf(g.tsType(t.Elem()))
+	case *types.Array:
+		return arrayOf(g.tsType(t.Elem()))
+	case *types.Map:
+		return "Record<string, " + g.tsType(t.Elem()) + ">"
+	case *types.Struct:
+		return g.structBody(t, "")
+	}
+	return "unknown"
+}
+
+func isByte(t types.Type) bool {
+	b, ok := types.Unalias(t).(*types.Basic)
+	return ok && b.Kind() == types.Byte
+}
+
+func arrayOf(s string) string {
+	if strings.Contains(s, " ") {
+		return "(" + s + ")[]"
+	}
+	return s + "[]"
+}
+
+func hasMethod(t types.Type, name string) bool {
+	ms := types.NewMethodSet(types.NewPointer(t))
+	return ms.Lookup(nil, name) != nil
+}
+
+// named declares named type, returns its name
+func (g *generator) named(t *types.Named) string {
+	key := types.TypeString(t, nil)
+	switch key {
+	case "time.Time":
+		return "string"
+	case "time.Duration":
+		return "number"
+	}
+	if name, ok := g.names[key]; ok {
+		return name
+	}
+	if hasMethod(t, "MarshalJSON") {
+		return "unknown"
+	}
+	if hasMethod(t, "MarshalText") {
+		return "string"
+	}
+
+	name := g.declName(t)
+	g.names[key] = name
+	g.decls = append(g.decls, name)
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		g.code[name] = "export interface " + name + " " + g.structBody(u, "") + "\n"
+	default:
+		g.code[name] = "export type " + name + " = " + g.tsType(u) + "\n"
+	}
+	return name
+}
+
+func (g *generator) declName(t *types.Named) string {
+	var b strings.Builder
+	b.WriteString(t.Obj().Name())
+	if args := t.TypeArgs(); args != nil {
+		for i := 0; i < args.Len(); i++ {
+			b.WriteString(exportName(funcName(g.tsType(args.At(i)))))
+		}
+	}
+	name := b.String()
+	if g.used[name] && t.Obj().Pkg() != nil {
+		name = exportName(t.Obj().Pkg().Name()) + name
+	}
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", b.String(), i)
+	}
+	g.used[name] = true
+	return name
+}
+
+func exportName(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// structBody returns TypeScript object type of struct using json tags
+func (g *generator) structBody(st *types.Struct, indent string) string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	g.fields(&b, st, indent+"\t")
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+func (g *generator) fields(b *strings.Builder, st *types.Struct, indent string) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+
+		tag := reflect.StructTag(st.Tag(i)).Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		optList := strings.Split(opts, ",")
+
+		// embedded struct without name is flattened
+		if f.Embedded() && name == "" {
+			ft := types.Unalias(f.Type())
+			if p, ok := ft.(*types.Pointer); ok {
+				ft = types.Unalias(p.Elem())
+			}
+			if est, ok := ft.Underlying().(*types.Struct); ok {
+				g.fields(b, est, indent)
+				continue
+			}
+		}
+		if !f.Exported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name()
+		}
+
+		typ := "string"
+		if !slices.Contains(optList, "string") {
+			typ = g.tsType(f.Type())
+		}
+		optional := ""
+		if slices.Contains(optList, "omitempty") || slices.Contains(optList, "omitzero") {
+			optional = "?"
+		}
+		fmt.Fprintf(b, "%s%s%s: %s\n", indent, tsKey(name), optional, typ)
+	}
+}
+
+// queryType declares query parameters type of t using query and form tags like arpc binding,
+// returns empty if t does not have any query or form tag
+func (g *generator) queryType(t types.Type) string {
+	key := "query " + types.TypeString(t, nil)
+	if name, ok := g.names[key]; ok {
+		return name
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+	if !g.queryFields(&b, st, "\t", map[*types.Struct]bool{}) {
+		return ""
+	}
+	body := "{\n" + b.String() + "}"
+	n, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return body
+	}
+
+	name := n.Obj().Name() + "Query"
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%sQuery%d", n.Obj().Name(), i)
+	}
+	g.used[name] = true
+	g.names[key] = name
+	g.decls = append(g.decls, name)
+	g.code[name] = "export interface " + name + " " + body + "\n"
+	return name
+}
+
+// queryFields writes fields that tagged with query or form tag, returns true if any field written
+func (g *generator) queryFields(b *strings.Builder, st *types.Struct, indent string, visited map[*types.Struct]bool) bool {
+	if visited[st] {
+		return false
+	}
+	visited[st] = true
+	defer delete(visited, st)
+
+	found := false
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name, ok := tag.Lookup("query")
+		if !ok {
+			name, ok = tag.Lookup("form")
+		}
+
+		// embedded struct without tag is flattened
+		if f.Embedded() && !ok {
+			ft := types.Unalias(f.Type())
+			if p, isPtr := ft.(*types.Pointer); isPtr {
+				if !f.Exported() {
+					continue
+				}
+				ft = types.Unalias(p.Elem())
+			}
+			if est, isStruct := ft.Underlying().(*types.Struct); isStruct {
+				found = g.queryFields(b, est, indent, visited) || found
+			}
+			continue
+		}
+		if !ok || name == "-" || !f.Exported() {
+			continue
+		}
+
+		ft := types.Unalias(f.Type())
+		if p, isPtr := ft.(*types.Pointer); isPtr {
+			ft = p.Elem()
+		}
+		fmt.Fprintf(b, "%s%s?: %s\n", indent, tsKey(name), g.tsType(ft))
+		found = true
+	}
+	return found
+}
+
+func tsKey(name string) string {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || r == '$' || (i > 0 && unicode.IsDigit(r))) {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+// generate generates TypeScript source
+func (g *generator) generate() []byte {
+	slices.SortStableFunc(g.endpoints, func(a, b *endpoint) int {
+		return strings.Compare(a.path, b.path)
+	})
+
+	// resolve types before write declarations
+	type method struct {
+		name, req, res string
+		ep             *endpoint
+	}
+	var methods []method
+	for _, ep := range g.endpoints {
+		m := method{name: ep.name, ep: ep, res: "Record<string, never>"}
+		if ep.req != nil {
+			// request is always allocated by arpc
+			t := ep.req
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if ep.query() {
+				m.req = g.queryType(t)
+				if m.req == "" {
+					log.Printf("%s: request %s does not have query or form tag, skip request", ep.path, types.TypeString(t, nil))
+				}
+			} else {
+				m.req = g.tsType(t)
+			}
+		}
+		if ep.res != nil {
+			m.res = g.tsType(ep.res)
+		}
+		methods = append(methods, m)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by arpc-gen. DO NOT EDIT.\n\n")
+	for _, name := range g.decls {
+		b.WriteString(g.code[name])
+		b.WriteString("\n")
+	}
+	b.WriteString(runtime)
+
+	b.WriteString("\treturn {\n")
+	for _, m := range methods {
+		param, arg := "", ""
+		if m.req != "" {
+			param, arg = "req: "+m.req, ", req"
+		}
+		if m.ep.sse {
+			if param != "" {
+				param = "req?: " + m.req
+			}
+			fmt.Fprintf(&b, "\t\t%s: (%s): EventSource => events(%q%s),\n", m.name, param, m.ep.path, arg)
+			continue
+		}
+		fmt.Fprintf(&b, "\t\t%s: (%s): Promise<%s> => call<%s>(%q, %q%s),\n", m.name, param, m.res, m.res, m.ep.method, m.ep.path, arg)
+	}
+	b.WriteString("\t}\n}\n")
+	return []byte(b.String())
+}
+
+const runtime = `export interface FieldError {
+	field: string
+	code?: string
+	message?: string
+}
+
+export class ArpcError extends Error {
+	constructor(public code: string, message: string, public fields?: FieldError[], public details?: unknown) {
+		super(message)
+		this.name = "ArpcError"
+	}
+}
+
+export class ProtocolError extends Error {
+	constructor(public code: string, message: string) {
+		super(message)
+		this.name = "ProtocolError"
+	}
+}
+
+export class InternalError extends Error {
+	constructor(public status: number, public id?: string) {
+		super("internal error")
+		this.name = "InternalError"
+	}
+}
+
+type Envelope<T> =
+	| { ok: true; result: T }
+	| { ok: false; error: { code?: string; message?: string; fields?: FieldError[]; details?: unknown; id?: string } }
+
+export interface ClientOptions {
+	baseURL: string
+	// headers is not sent on sse requests, EventSource can not send custom headers
+	headers?: Record<string, string> | (() => Record<string, string> | Promise<Record<string, string>>)
+	fetch?: typeof fetch
+}
+
+function query(req: unknown): string {
+	if (req == null) {
+		return ""
+	}
+	const params = new URLSearchParams()
+	for (const [k, v] of Object.entries(req as Record<string, unknown>)) {
+		if (v == null) {
+			continue
+		}
+		if (Array.isArray(v)) {
+			v.forEach((x) => params.append(k, String(x)))
+		} else {
+			params.append(k, String(v))
+		}
+	}
+	const s = params.toString()
+	return s ? "?" + s : ""
+}
+
+export function createClient(options: ClientOptions) {
+	const baseURL = options.baseURL.replace(/\/$/, "")
+	const doFetch = options.fetch ?? fetch
+
+	async function call<Res>(method: string, path: string, req?: unknown): Promise<Res> {
+		const headers: Record<string, string> = {
+			Accept: "application/json",
+			...(typeof options.headers === "function" ? await options.headers() : options.headers),
+		}
+		let url = baseURL + path
+		let body: string | undefined
+		if (method === "GET" || method === "HEAD") {
+			url += query(req)
+		} else {
+			headers["Content-Type"] = "application/json"
+			body = JSON.stringify(req ?? {})
+		}
+
+		const resp = await doFetch(url, { method, headers, body })
+		let env: Envelope<Res>
+		try {
+			env = await resp.json()
+		} catch {
+			throw new InternalError(resp.status, resp.headers.get("X-Error-Id") ?? undefined)
+		}
+		if (env.ok) {
+			return env.result
+		}
+		if (resp.status === 400) {
+			throw new ProtocolError(env.error.code ?? "", env.error.message ?? "")
+		}
+		if (resp.status >= 500) {
+			throw new InternalError(resp.status, env.error.id)
+		}
+		throw new ArpcError(env.error.code ?? "", env.error.message ?? "", env.error.fields, env.error.details)
+	}
+
+	// EventSource can not send custom headers, options.headers is not sent
+	function events(path: string, req?: unknown): EventSource {
+		return new EventSource(baseURL + path + query(req))
+	}
+
+`
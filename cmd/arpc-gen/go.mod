@@ -0,0 +1,19 @@
+module github.com/acoshift/arpc/v2/cmd/arpc-gen
+
+go 1.23
+
+require (
+	github.com/acoshift/arpc/v2 v2.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/acoshift/arpc/v2 => ../..
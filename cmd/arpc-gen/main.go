@@ -0,0 +1,61 @@
+// Command arpc-gen generates TypeScript client from arpc handlers.
+//
+// Usage:
+//
+//	arpc-gen [-o client.ts] [packages]
+//
+// arpc-gen finds handlers mounted with Manager.Mount, Mounter.Mount,
+// and mux.Handle(pattern, Manager.Handler(f)) then generates TypeScript types
+// from request and result structs with a fetch-based client.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const loadMode = packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("arpc-gen: ")
+
+	out := flag.String("o", "", "output file, default is stdout")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: arpc-gen [-o client.ts] [packages]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode}, patterns...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		os.Exit(1)
+	}
+
+	g := newGenerator()
+	for _, pkg := range pkgs {
+		g.findEndpoints(pkg)
+	}
+	src := g.generate()
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = os.WriteFile(*out, src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestGenerate(t *testing.T) {
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode}, "./testdata/example")
+	if !assert.NoError(t, err) || !assert.Zero(t, packages.PrintErrors(pkgs)) {
+		return
+	}
+
+	g := newGenerator()
+	for _, pkg := range pkgs {
+		g.findEndpoints(pkg)
+	}
+	src := string(g.generate())
+
+	assert.Contains(t, src, "export type Status = string\n")
+	assert.Contains(t, src, `export interface User {
+	id: number
+	name: string
+	email?: string | null
+	status: Status
+	tags: string[]
+	meta: Record<string, unknown>
+	createdAt: string
+	friends: (User | null)[]
+	count: string
+}
+`)
+	assert.Contains(t, src, `export interface GetUserParams {
+	id: number
+}
+`)
+	assert.Contains(t, src, `export interface GetUserParamsQuery {
+	id?: number
+}
+`)
+	assert.Contains(t, src, `export interface ListUsersParamsQuery {
+	page?: number
+	tag?: string[]
+	since?: string
+}
+`)
+	assert.Contains(t, src, `userGet: (req: GetUserParams): Promise<User | null> => call<User | null>("POST", "/user.get", req),`)
+	assert.Contains(t, src, `userFind: (req: GetUserParamsQuery): Promise<User | null> => call<User | null>("GET", "/user.find", req),`)
+	assert.Contains(t, src, `userList: (req: ListUsersParamsQuery): Promise<(User | null)[]> => call<(User | null)[]>("GET", "/user.list", req),`)
+	assert.Contains(t, src, `userCount: (): Promise<number> => call<number>("GET", "/user.count"),`)
+	assert.Contains(t, src, `userDelete: (req: GetUserParams): Promise<Record<string, never>> => call<Record<string, never>>("POST", "/user.delete", req),`)
+	assert.Contains(t, src, `events: (): EventSource => events("/events"),`)
+	assert.Contains(t, src, `userWatch: (req?: GetUserParamsQuery): EventSource => events("/user.watch", req),`)
+	assert.Contains(t, src, `ticks: (): EventSource => events("/ticks"),`)
+	assert.NotContains(t, src, "raw")
+}
+
+func TestFuncName(t *testing.T) {
+	assert.Equal(t, "userGet", funcName("/user.get"))
+	assert.Equal(t, "hello", funcName("/hello"))
+	assert.Equal(t, "apiV1ListItems", funcName("/api/v1/list-items"))
+	assert.Equal(t, "index", funcName("/"))
+}
@@ -0,0 +1,91 @@
+package example
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/acoshift/arpc/v2"
+)
+
+type Status string
+
+type Base struct {
+	ID int64 `json:"id"`
+}
+
+type User struct {
+	Base
+	Name      string         `json:"name"`
+	Email     *string        `json:"email,omitempty"`
+	Status    Status         `json:"status"`
+	Tags      []string       `json:"tags"`
+	Meta      map[string]any `json:"meta"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Friends   []*User        `json:"friends"`
+	Count     int64          `json:"count,string"`
+	secret    string
+}
+
+type GetUserParams struct {
+	ID int64 `json:"id" query:"id"`
+}
+
+type Page struct {
+	Page int `query:"page"`
+}
+
+type ListUsersParams struct {
+	Page
+	Tags   []string `form:"tag"`
+	Since  *string  `query:"since"`
+	Filter string   `json:"filter"`
+}
+
+type CountUsersParams struct {
+	Status Status `json:"status"`
+}
+
+func ListUsers(ctx context.Context, req *ListUsersParams) ([]*User, error) {
+	return nil, nil
+}
+
+func CountUsers(ctx context.Context, req *CountUsersParams) (int, error) {
+	return 0, nil
+}
+
+func GetUser(ctx context.Context, req *GetUserParams) (*User, error) {
+	return nil, nil
+}
+
+func DeleteUser(ctx context.Context, req *GetUserParams) error {
+	return nil
+}
+
+func Events(ctx context.Context, w arpc.SSEResponseWriter) error {
+	return nil
+}
+
+func WatchUser(ctx context.Context, req *GetUserParams) (<-chan *User, error) {
+	return nil, nil
+}
+
+func Ticks(ctx context.Context, send func(int) error) error {
+	return nil
+}
+
+func Raw(w http.ResponseWriter, r *http.Request) {}
+
+func Mount(mux *http.ServeMux) {
+	am := arpc.New()
+	m := am.Mounter(mux)
+	m.Mount("/user.get", GetUser)
+	am.Mount(mux, "POST /user.delete", DeleteUser)
+	mux.Handle("/events", am.Handler(Events))
+	mux.Handle("GET /user.find", arpc.Handle(am, GetUser))
+	mux.Handle("/raw", am.Handler(Raw))
+	m.Mount("/user.watch", WatchUser)
+	m.Mount("/ticks", Ticks)
+	m.Mount("GET /user.list", ListUsers)
+	m.Mount("GET /user.count", CountUsers)
+}
@@ -0,0 +1,208 @@
+package arpc
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Codec decodes request and encodes response for a media type
+type Codec interface {
+	// Decode decodes request body into v,
+	// returns ErrUnsupported if codec can not decode into v
+	Decode(r *http.Request, v any) error
+
+	// Encode encodes v into w
+	Encode(w io.Writer, v any) error
+}
+
+const (
+	mediaTypeJSON      = "application/json"
+	mediaTypeForm      = "application/x-www-form-urlencoded"
+	mediaTypeMultipart = "multipart/form-data"
+)
+
+// JSONCodec encodes and decodes using encoding/json
+type JSONCodec struct{}
+
+func (JSONCodec) Decode(r *http.Request, v any) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// formCodec decodes url-encoded form, can not use to encode response
+type formCodec struct{}
+
+func (formCodec) Decode(r *http.Request, v any) error {
+	err := parsePostForm(r)
+	if err != nil {
+		return err
+	}
+	if v, ok := v.(FormUnmarshaler); ok {
+		return v.UnmarshalForm(r.PostForm)
+	}
+	if bindable(v) {
+		return bindValues(v, r.PostForm, r.URL.Query())
+	}
+	return ErrUnsupported
+}
+
+func (formCodec) Encode(w io.Writer, v any) error {
+	return ErrUnsupported
+}
+
+// multipartCodec decodes multipart form, can not use to encode response
+type multipartCodec struct{}
+
+func (multipartCodec) Decode(r *http.Request, v any) error {
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		return err
+	}
+	if v, ok := v.(MultipartFormUnmarshaler); ok {
+		return v.UnmarshalMultipartForm(r.MultipartForm)
+	}
+	if bindable(v) {
+		return bindValues(v, r.MultipartForm.Value, r.URL.Query())
+	}
+	return ErrUnsupported
+}
+
+func (multipartCodec) Encode(w io.Writer, v any) error {
+	return ErrUnsupported
+}
+
+var defaultCodecs = map[string]Codec{
+	mediaTypeJSON:      JSONCodec{},
+	mediaTypeForm:      formCodec{},
+	mediaTypeMultipart: multipartCodec{},
+}
+
+// RegisterCodec registers codec for media type,
+// registered codec is used for both decode request and encode response
+func (m *Manager) RegisterCodec(mediaType string, c Codec) {
+	if m.codecs == nil {
+		m.codecs = make(map[string]Codec)
+	}
+	if _, exists := m.codecs[mediaType]; !exists {
+		m.encodeTypes = append(m.encodeTypes, mediaType)
+	}
+	m.codecs[mediaType] = c
+}
+
+// requestCodec returns codec for decode request with media type mt
+func (m *Manager) requestCodec(mt string) Codec {
+	if c, ok := m.codecs[mt]; ok {
+		return c
+	}
+	return defaultCodecs[mt]
+}
+
+func (m *Manager) defaultMediaType() string {
+	if m.DefaultMediaType == "" {
+		return mediaTypeJSON
+	}
+	return m.DefaultMediaType
+}
+
+// encodable returns true if media type mt can use to encode response
+func (m *Manager) encodable(mt string) bool {
+	return mt == mediaTypeJSON || slices.Contains(m.encodeTypes, mt)
+}
+
+func (m *Manager) encodeCodec(mt string) Codec {
+	if c, ok := m.codecs[mt]; ok {
+		return c
+	}
+	return JSONCodec{}
+}
+
+// negotiate selects response media type from Accept header
+func (m *Manager) negotiate(r *http.Request) (string, error) {
+	def := m.defaultMediaType()
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return def, nil
+	}
+
+	type mediaRange struct {
+		mt string
+		q  float64
+	}
+	var ranges []mediaRange
+	for _, x := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(x))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, mediaRange{mt, q})
+	}
+	slices.SortStableFunc(ranges, func(a, b mediaRange) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+
+	candidates := append([]string{def, mediaTypeJSON}, m.encodeTypes...)
+	for _, x := range ranges {
+		// problem client accepts result in default media type
+		if x.mt == mediaTypeProblem && m.acceptProblem {
+			return def, nil
+		}
+		for _, mt := range candidates {
+			if matchMediaRange(x.mt, mt) && m.encodable(mt) {
+				return mt, nil
+			}
+		}
+	}
+	return "", ErrNotAcceptable
+}
+
+func matchMediaRange(pattern, mt string) bool {
+	if pattern == "*/*" || pattern == mt {
+		return true
+	}
+	if p, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mt, p+"/")
+	}
+	return false
+}
+
+// responseCodec returns media type and codec for encode response,
+// fallback to default media type when client does not accept any codec
+func (m *Manager) responseCodec(r *http.Request) (string, Codec) {
+	mt, err := m.negotiate(r)
+	if err != nil {
+		mt = m.defaultMediaType()
+	}
+	return mt, m.encodeCodec(mt)
+}
+
+func contentType(mt string) string {
+	if mt == mediaTypeJSON || strings.HasPrefix(mt, "text/") {
+		return mt + "; charset=utf-8"
+	}
+	return mt
+}
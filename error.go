@@ -1,7 +1,10 @@
 package arpc
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 )
 
 // OKError implements this interface to mark errors as 200
@@ -12,9 +15,12 @@ type OKError interface {
 // Error always return 200 status with false ok value
 // use this error for validate, precondition failed, etc.
 type Error struct {
-	code string
-	msg  string
-	err  error
+	code    string
+	msg     string
+	err     error
+	details any
+	ec      *ErrorCode     // catalog error code
+	params  map[string]any // catalog message parameters
 }
 
 // OKError implements OKError
@@ -39,7 +45,8 @@ func (err *Error) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Code    string `json:"code,omitempty"`
 		Message string `json:"message,omitempty"`
-	}{err.code, err.msg})
+		Details any    `json:"details,omitempty"`
+	}{err.code, err.msg, err.details})
 }
 
 // Code returns error code
@@ -52,6 +59,26 @@ func (err *Error) Message() string {
 	return err.msg
 }
 
+// Details returns error details
+func (err *Error) Details() any {
+	return err.details
+}
+
+// WithDetails returns copy of err with details,
+// details must be JSON serializable
+func (err *Error) WithDetails(details any) *Error {
+	e := *err
+	e.details = details
+	return &e
+}
+
+// WithCause returns copy of err that wraps cause
+func (err *Error) WithCause(cause error) *Error {
+	e := *err
+	e.err = cause
+	return &e
+}
+
 // NewError creates new Error with message
 func NewError(message string) error {
 	return &Error{msg: message}
@@ -62,23 +89,29 @@ func NewErrorCode(code, message string) error {
 	return &Error{code: code, msg: message}
 }
 
+// NewErrorDetails creates new Error with code, message and details
+func NewErrorDetails(code, message string, details any) error {
+	return &Error{code: code, msg: message, details: details}
+}
+
 func wrapError(err error) error {
 	return &Error{msg: err.Error(), err: err}
 }
 
-// WrapError wraps given error with OKError
+// WrapError wraps given error with OKError,
+// returns err as is if it already wraps OKError or ProtocolError
 func WrapError(err error) error {
 	if err == nil {
 		return nil
 	}
-	switch err.(type) {
-	case *Error:
-		return err
-	case *ProtocolError:
+	var (
+		okErr OKError
+		pErr  *ProtocolError
+	)
+	if errors.As(err, &okErr) || errors.As(err, &pErr) {
 		return err
-	default:
-		return wrapError(err)
 	}
+	return wrapError(err)
 }
 
 // ProtocolError always returns 400 status with false ok value
@@ -98,10 +131,43 @@ func (err *ProtocolError) Error() string {
 
 // predefined errors
 var (
-	ErrNotFound    = NewProtocolError("", "not found")
-	ErrUnsupported = NewProtocolError("", "unsupported content type")
+	ErrNotFound         = NewProtocolError("", "not found")
+	ErrUnsupported      = NewProtocolError("", "unsupported content type")
+	ErrMethodNotAllowed = NewProtocolError("", "method not allowed")
+	ErrNotAcceptable    = NewProtocolError("", "not acceptable")
 )
 
+// InternalError is the internal error with ID,
+// the ID is sent to client to look up the original error in server log
+type InternalError struct {
+	ID  string
+	Err error
+}
+
+// Error implements error
+func (err *InternalError) Error() string {
+	return "internal error " + err.ID + ": " + err.Err.Error()
+}
+
+// Unwrap implements errors.Unwrap
+func (err *InternalError) Unwrap() error {
+	return err.Err
+}
+
+// MarshalJSON implements json.Marshaler
+func (err *InternalError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID string `json:"id"`
+	}{err.ID})
+}
+
+// NewErrorID generates random error ID
+func NewErrorID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
 type internalError struct{}
 
 func (internalError) Error() string { return "internal error" }
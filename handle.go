@@ -0,0 +1,118 @@
+package arpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// Handle creates type-safe handler from f,
+// the request is decoded into Req and the result is encoded without reflection
+func Handle[Req, Res any](m *Manager, f func(ctx context.Context, req *Req) (*Res, error)) http.Handler {
+	if m.Validate {
+		m.checkValidate(reflect.TypeFor[Req](), map[reflect.Type]bool{})
+	}
+	p := &pipeline{
+		newRequest: func() any { return new(Req) },
+		call: func(w http.ResponseWriter, r *http.Request, req any) (any, error) {
+			return f(r.Context(), req.(*Req))
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serve(w, r, p)
+	})
+}
+
+// HandleNoRequest creates type-safe handler from f that does not take any request
+func HandleNoRequest[Res any](m *Manager, f func(ctx context.Context) (*Res, error)) http.Handler {
+	p := &pipeline{
+		call: func(w http.ResponseWriter, r *http.Request, req any) (any, error) {
+			return f(r.Context())
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serve(w, r, p)
+	})
+}
+
+// HandleNoResult creates type-safe handler from f that does not return any result,
+// the response result will be an empty object
+func HandleNoResult[Req any](m *Manager, f func(ctx context.Context, req *Req) error) http.Handler {
+	if m.Validate {
+		m.checkValidate(reflect.TypeFor[Req](), map[reflect.Type]bool{})
+	}
+	p := &pipeline{
+		newRequest: func() any { return new(Req) },
+		call: func(w http.ResponseWriter, r *http.Request, req any) (any, error) {
+			err := f(r.Context(), req.(*Req))
+			if err != nil {
+				return nil, err
+			}
+			return _empty, nil
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serve(w, r, p)
+	})
+}
+
+// pipeline is the steps of handler
+type pipeline struct {
+	newRequest func() any                                                         // allocates request, nil if handler does not take request
+	call       func(w http.ResponseWriter, r *http.Request, req any) (any, error) // calls handler
+	write      func(w http.ResponseWriter, r *http.Request, req, res any)         // writes result, nil encodes result
+	raw        bool                                                               // result is not encoded, e.g. sse and stream
+}
+
+// serve runs handler pipeline,
+// recovers panic, checks request, decodes request, calls handler then writes result
+func (m *Manager) serve(w http.ResponseWriter, r *http.Request, p *pipeline) {
+	var req any
+	if m.Recover {
+		rw := &recoverResponseWriter{ResponseWriter: w}
+		w = rw
+		defer m.recoverPanic(rw, r, &req)
+	}
+
+	err := m.checkRequest(w, r, p.raw)
+	if err != nil {
+		m.encodeAndHookError(w, r, nil, err)
+		return
+	}
+
+	if p.newRequest != nil {
+		req = p.newRequest()
+		err = m.decodeRequest(r, req)
+		if err != nil {
+			m.encodeAndHookError(w, r, req, err)
+			return
+		}
+	}
+
+	res, err := p.call(w, r, req)
+	if sErr, ok := err.(*startedError); ok {
+		// response already started, ends the response without encode error
+		m.hookError(w, r, req, m.withErrorID(m.wrapError(sErr.err)))
+		return
+	}
+	if err != nil {
+		m.encodeAndHookError(w, r, req, err)
+		return
+	}
+
+	if p.write != nil {
+		p.write(w, r, req, res)
+		return
+	}
+	m.encodeResult(w, r, req, res)
+}
+
+// startedError is the error returned by handler after the response started,
+// i.e. sse handler, the error can not be encoded
+type startedError struct {
+	err error
+}
+
+func (err *startedError) Error() string { return err.err.Error() }
+
+func (err *startedError) Unwrap() error { return err.err }
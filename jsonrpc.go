@@ -0,0 +1,231 @@
+package arpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError       = -32700
+	JSONRPCInvalidRequest   = -32600
+	JSONRPCMethodNotFound   = -32601
+	JSONRPCInvalidParams    = -32602
+	JSONRPCInternalError    = -32603
+	JSONRPCApplicationError = -32000 // OKError returns from function
+)
+
+// JSONRPC is the JSON-RPC 2.0 handler
+type JSONRPC struct {
+	m       *Manager
+	methods map[string]*handlerFunc
+}
+
+// JSONRPC creates new JSON-RPC 2.0 handler,
+// functions registered to the handler use the same signature rules as Handler
+func (m *Manager) JSONRPC() *JSONRPC {
+	return &JSONRPC{
+		m:       m,
+		methods: make(map[string]*handlerFunc),
+	}
+}
+
+// Register registers f as method
+func (h *JSONRPC) Register(method string, f any) {
+	fn := parseHandlerFunc(f)
+	if fn.hasWriter {
+		panic("arpc: jsonrpc method can not use response writer")
+	}
+	if fn.isStream || fn.isChan {
+		panic("arpc: jsonrpc method can not return stream")
+	}
+	if _, exists := h.methods[method]; exists {
+		panic("arpc: duplicate jsonrpc method")
+	}
+	h.methods[method] = fn
+}
+
+type jsonrpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonrpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// JSONRPCError is the JSON-RPC 2.0 error object
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (err *JSONRPCError) Error() string {
+	return err.Message
+}
+
+var jsonNull = json.RawMessage("null")
+
+func (h *JSONRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.write(w, jsonrpcErrorResponse(nil, &JSONRPCError{Code: JSONRPCParseError, Message: "Parse error"}))
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	// batch
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []json.RawMessage
+		err = json.Unmarshal(body, &reqs)
+		if err != nil {
+			h.write(w, jsonrpcErrorResponse(nil, &JSONRPCError{Code: JSONRPCParseError, Message: "Parse error"}))
+			return
+		}
+		if len(reqs) == 0 {
+			h.write(w, jsonrpcErrorResponse(nil, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "Invalid Request"}))
+			return
+		}
+
+		resps := make([]*jsonrpcResponse, 0, len(reqs))
+		for _, req := range reqs {
+			if resp := h.serve(w, r, req); resp != nil {
+				resps = append(resps, resp)
+			}
+		}
+		if len(resps) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.write(w, resps)
+		return
+	}
+
+	var req json.RawMessage
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		h.write(w, jsonrpcErrorResponse(nil, &JSONRPCError{Code: JSONRPCParseError, Message: "Parse error"}))
+		return
+	}
+	resp := h.serve(w, r, req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.write(w, resp)
+}
+
+func (h *JSONRPC) write(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
+
+func jsonrpcErrorResponse(id json.RawMessage, err *JSONRPCError) *jsonrpcResponse {
+	if id == nil {
+		id = jsonNull
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Error: err, ID: id}
+}
+
+// serve serves single request, returns nil for notification
+func (h *JSONRPC) serve(w http.ResponseWriter, r *http.Request, msg json.RawMessage) *jsonrpcResponse {
+	var req jsonrpcRequest
+	err := json.Unmarshal(msg, &req)
+	if err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return jsonrpcErrorResponse(nil, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "Invalid Request"})
+	}
+	notification := req.ID == nil
+
+	res, err := h.call(w, r, &req)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		return jsonrpcErrorResponse(req.ID, h.toJSONRPCError(r, err))
+	}
+	if res == nil {
+		// result member is required on success
+		res = jsonNull
+	}
+	return &jsonrpcResponse{JSONRPC: "2.0", Result: res, ID: req.ID}
+}
+
+func (h *JSONRPC) call(w http.ResponseWriter, r *http.Request, req *jsonrpcRequest) (any, error) {
+	fn, ok := h.methods[req.Method]
+	if !ok {
+		return nil, &JSONRPCError{Code: JSONRPCMethodNotFound, Message: "Method not found"}
+	}
+
+	var (
+		in    any
+		rfReq reflect.Value
+	)
+	if fn.hasRequest() {
+		rfReq = fn.newRequest()
+		in = rfReq.Interface()
+		if len(req.Params) > 0 && !bytes.Equal(req.Params, jsonNull) {
+			err := json.Unmarshal(req.Params, in)
+			if err != nil {
+				return nil, &JSONRPCError{Code: JSONRPCInvalidParams, Message: "Invalid params", Data: err.Error()}
+			}
+		}
+
+		err := h.m.validateRequest(r.Context(), in)
+		if err != nil {
+			return nil, h.hookError(w, r, in, err)
+		}
+	}
+
+	res, err := fn.call(h.m, w, r, rfReq)
+	if err != nil {
+		return nil, h.hookError(w, r, in, err)
+	}
+	h.m.hookOK(w, r, in, res)
+	return res, nil
+}
+
+func (h *JSONRPC) hookError(w http.ResponseWriter, r *http.Request, req any, err error) error {
+	err = h.m.withErrorID(h.m.wrapError(err))
+	h.m.hookError(w, r, req, err)
+	return err
+}
+
+// toJSONRPCError converts arpc error to JSON-RPC error object
+func (h *JSONRPC) toJSONRPCError(r *http.Request, err error) *JSONRPCError {
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	status, err := h.m.errorStatus(err)
+	err = localizeError(r, err)
+	switch status {
+	case http.StatusOK:
+		var appErr *Error
+		if errors.As(err, &appErr) {
+			return &JSONRPCError{Code: JSONRPCApplicationError, Message: appErr.Message(), Data: err}
+		}
+		return &JSONRPCError{Code: JSONRPCApplicationError, Message: err.Error(), Data: err}
+	case http.StatusBadRequest:
+		if err == ErrNotFound {
+			return &JSONRPCError{Code: JSONRPCMethodNotFound, Message: "Method not found"}
+		}
+		return &JSONRPCError{Code: JSONRPCInvalidRequest, Message: err.(*ProtocolError).Message, Data: err}
+	default:
+		if _, ok := err.(*InternalError); ok {
+			return &JSONRPCError{Code: JSONRPCInternalError, Message: "Internal error", Data: err}
+		}
+		return &JSONRPCError{Code: JSONRPCInternalError, Message: "Internal error"}
+	}
+}
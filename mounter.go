@@ -1,11 +1,69 @@
 package arpc
 
+import (
+	"net/http"
+	"strings"
+)
+
 type Mounter struct {
 	Manager *Manager
 	Mux     Mux
+
+	BatchConcurrency int // max concurrent calls in a batch, zero is unlimited
+	BatchLimit       int // max calls in a batch, zero is DefaultBatchLimit
+
+	handlers map[string]*mountedHandler
 }
 
-// Mount mounts the handler to the mux
+type mountedHandler struct {
+	pattern string
+	fn      *handlerFunc
+	h       http.Handler
+}
+
+// Mount mounts the handler to the mux,
+// panics if pattern already mounted
 func (m *Mounter) Mount(pattern string, f any) {
-	m.Manager.Mount(m.Mux, pattern, f)
+	key := patternKey(pattern)
+	if _, exists := m.handlers[key]; exists {
+		panic("arpc: duplicate mount pattern " + pattern)
+	}
+
+	fn := parseHandlerFunc(f)
+	h := m.Manager.handler(fn)
+	m.Mux.Handle(pattern, h)
+
+	if m.handlers == nil {
+		m.handlers = make(map[string]*mountedHandler)
+	}
+	m.handlers[key] = &mountedHandler{
+		pattern: pattern,
+		fn:      fn,
+		h:       h,
+	}
+}
+
+// patternKey returns registry key from mux pattern, i.e. "POST  /user.get" returns "POST /user.get"
+func patternKey(pattern string) string {
+	if method := patternMethod(pattern); method != "" {
+		return method + " " + patternPath(pattern)
+	}
+	return pattern
+}
+
+// patternMethod returns method from mux pattern, i.e. "POST /user.get" returns "POST",
+// returns empty if pattern matches all methods
+func patternMethod(pattern string) string {
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		return pattern[:i]
+	}
+	return ""
+}
+
+// patternPath returns path from mux pattern, i.e. "POST /user.get" returns "/user.get"
+func patternPath(pattern string) string {
+	if i := strings.LastIndexByte(pattern, ' '); i >= 0 {
+		return pattern[i+1:]
+	}
+	return pattern
 }
@@ -0,0 +1,344 @@
+package arpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// OpenAPIInfo is the info object of OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPI builds OpenAPI 3.1 document from mounted handlers
+func (m *Mounter) OpenAPI(info OpenAPIInfo) map[string]any {
+	g := schemaGenerator{
+		schemas: map[string]any{
+			"Error": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"code":    map[string]any{"type": "string"},
+					"message": map[string]any{"type": "string"},
+					"details": map[string]any{},
+					"fields": map[string]any{
+						"type": "array",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"field":   map[string]any{"type": "string"},
+								"code":    map[string]any{"type": "string"},
+								"message": map[string]any{"type": "string"},
+							},
+							"required": []string{"field"},
+						},
+					},
+				},
+			},
+		},
+		names: make(map[reflect.Type]string),
+	}
+
+	patterns := make([]string, 0, len(m.handlers))
+	for p := range m.handlers {
+		patterns = append(patterns, p)
+	}
+	slices.Sort(patterns)
+
+	// path mounted with many methods has an operation per method
+	methods := make(map[string]int)
+	for _, mh := range m.handlers {
+		methods[patternPath(mh.pattern)]++
+	}
+
+	paths := make(map[string]any)
+	for _, p := range patterns {
+		mh := m.handlers[p]
+		path := patternPath(mh.pattern)
+		method := patternMethod(mh.pattern)
+		if method == "" {
+			method = http.MethodPost
+		}
+
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+		op := g.operation(path, method, mh.fn)
+		if methods[path] > 1 {
+			op["operationId"] = strings.ToLower(method) + "_" + op["operationId"].(string)
+		}
+		item[strings.ToLower(method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+		},
+	}
+}
+
+// OpenAPIHandler serves OpenAPI document as json
+func (m *Mounter) OpenAPIHandler(info OpenAPIInfo) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(m.OpenAPI(info))
+	})
+}
+
+func errorEnvelopeSchema(errSchema any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"ok":    map[string]any{"const": false},
+			"error": errSchema,
+		},
+		"required": []string{"ok", "error"},
+	}
+}
+
+func jsonContent(schema any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{
+			"schema": schema,
+		},
+	}
+}
+
+func (g *schemaGenerator) operation(path, method string, fn *handlerFunc) map[string]any {
+	errRef := map[string]any{"$ref": "#/components/schemas/Error"}
+
+	op := map[string]any{
+		"operationId": strings.Trim(path, "/"),
+	}
+	switch {
+	case !fn.hasRequest():
+	case method == http.MethodGet || method == http.MethodHead:
+		// GET and HEAD request decodes from query using query and form tags
+		if params := g.queryParameters(fn.infType); len(params) > 0 {
+			op["parameters"] = params
+		}
+	default:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(g.schema(fn.infType)),
+		}
+	}
+
+	var okContent map[string]any
+	_, hasSSE := fn.mapIn[miSSEResponseWriter]
+	_, hasSend := fn.mapIn[miSSESend]
+	if hasSSE || hasSend || fn.isChan {
+		okContent = map[string]any{
+			"text/event-stream": map[string]any{
+				"schema": map[string]any{"type": "string"},
+			},
+		}
+	} else if fn.isStream {
+		okContent = map[string]any{
+			"application/octet-stream": map[string]any{
+				"schema": map[string]any{"type": "string", "format": "binary"},
+			},
+		}
+	} else {
+		resultSchema := map[string]any{"type": "object"}
+		if i, ok := fn.mapOut[miAny]; ok {
+			resultSchema = g.schema(fn.fv.Type().Out(i))
+		}
+		okContent = jsonContent(map[string]any{
+			"oneOf": []any{
+				map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"ok":     map[string]any{"const": true},
+						"result": resultSchema,
+					},
+					"required": []string{"ok", "result"},
+				},
+				errorEnvelopeSchema(errRef),
+			},
+		})
+	}
+
+	op["responses"] = map[string]any{
+		"200": map[string]any{
+			"description": "OK",
+			"content":     okContent,
+		},
+		"400": map[string]any{
+			"description": "Bad Request",
+			"content":     jsonContent(errorEnvelopeSchema(errRef)),
+		},
+		"500": map[string]any{
+			"description": "Internal Server Error",
+			"content": jsonContent(errorEnvelopeSchema(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{"type": "string"},
+				},
+			})),
+		},
+	}
+	return op
+}
+
+// queryParameters returns query parameters from query and form tags of struct t
+func (g *schemaGenerator) queryParameters(t reflect.Type) []any {
+	var params []any
+	for _, f := range bindFields(t) {
+		params = append(params, map[string]any{
+			"name":   f.name,
+			"in":     "query",
+			"schema": g.schema(t.FieldByIndex(f.index).Type),
+		})
+	}
+	return params
+}
+
+// schemaGenerator generates JSON schema from go types
+type schemaGenerator struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]any{"type": "integer"}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return map[string]any{}
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + g.define(t)}
+	}
+	return map[string]any{}
+}
+
+// define defines named struct type in components, returns schema name
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, exists := g.schemas[name]; exists {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndexByte(pkg, '/')+1:]
+		name = strings.ReplaceAll(pkg, ".", "_") + "." + name
+	}
+	g.names[t] = name
+	g.schemas[name] = map[string]any{} // placeholder for recursive type
+	g.schemas[name] = g.structSchema(t)
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	g.fields(t, props, &required)
+
+	s := map[string]any{
+		"type":       "object",
+		"properties": props,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *schemaGenerator) fields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded struct without name is flattened
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, props, required)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		var s map[string]any
+		if slices.Contains(strings.Split(opts, ","), "string") {
+			s = map[string]any{"type": "string"}
+		} else {
+			s = g.schema(sf.Type)
+		}
+		props[name] = s
+
+		optional := slices.ContainsFunc(strings.Split(opts, ","), func(s string) bool {
+			return s == "omitempty" || s == "omitzero"
+		})
+		if sf.Type.Kind() != reflect.Ptr && !optional {
+			*required = append(*required, name)
+		}
+	}
+}
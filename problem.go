@@ -0,0 +1,117 @@
+package arpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const mediaTypeProblem = "application/problem+json"
+
+// Problem is the RFC 9457 problem details document,
+// arpc error code and message are encoded as extension members
+type Problem struct {
+	Type     string        `json:"type,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Status   int           `json:"status,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Code     string        `json:"code,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Fields   []*FieldError `json:"fields,omitempty"`
+	Details  any           `json:"details,omitempty"`
+	ID       string        `json:"id,omitempty"`
+}
+
+// Error implements error
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// ProblemEncoder returns ErrorEncoder that encodes error as application/problem+json,
+// okStatus is the status for OKError, 0 is 200,
+// client that accepts only application/problem+json passes accept negotiation
+func (m *Manager) ProblemEncoder(okStatus int) ErrorEncoder {
+	if okStatus == 0 {
+		okStatus = http.StatusOK
+	}
+	m.acceptProblem = true
+
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		status, err := m.errorStatus(err)
+		err = localizeError(r, err)
+		if status == http.StatusOK {
+			status = okStatus
+		}
+
+		p := Problem{
+			Title:    http.StatusText(status),
+			Status:   status,
+			Instance: r.URL.Path,
+		}
+		var (
+			appErr *Error
+			vErr   *ValidationError
+			pErr   *ProtocolError
+		)
+		switch {
+		case errors.As(err, &vErr):
+			p.Code = ValidationErrorCode
+			p.Message = vErr.Message()
+			p.Detail = p.Message
+			p.Fields = vErr.Fields
+		case errors.As(err, &appErr):
+			p.Code = appErr.Code()
+			p.Message = appErr.Message()
+			p.Detail = p.Message
+			p.Details = appErr.Details()
+		case errors.As(err, &pErr):
+			p.Code = pErr.Code
+			p.Message = pErr.Message
+			p.Detail = p.Message
+		case status != http.StatusInternalServerError:
+			p.Detail = err.Error()
+		default:
+			if iErr, ok := err.(*InternalError); ok {
+				p.ID = iErr.ID
+			}
+		}
+
+		w.Header().Set("Content-Type", mediaTypeProblem)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(&p)
+	}
+}
+
+// ParseProblem parses application/problem+json document
+func ParseProblem(data []byte) (*Problem, error) {
+	var p Problem
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Err converts problem into arpc error,
+// 400 status returns *ProtocolError, 5xx status returns p,
+// other statuses return *ValidationError or *Error
+func (p *Problem) Err() error {
+	msg := p.Message
+	if msg == "" {
+		msg = p.Detail
+	}
+	switch {
+	case p.Status == http.StatusBadRequest:
+		return &ProtocolError{Code: p.Code, Message: msg}
+	case p.Status >= http.StatusInternalServerError:
+		return p
+	case p.Code == ValidationErrorCode && p.Fields != nil:
+		return NewValidationError(p.Fields...)
+	default:
+		return NewErrorDetails(p.Code, msg, p.Details)
+	}
+}
@@ -0,0 +1,92 @@
+package arpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is the error recovered from panic in handler,
+// PanicError always encodes as internal error
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// recoverResponseWriter tracks whether response already started
+type recoverResponseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *recoverResponseWriter) WriteHeader(statusCode int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoverResponseWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recoverResponseWriter) Flush() {
+	w.wrote = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection i.e. websocket upgrade,
+// returns http.ErrNotSupported if the response writer can not hijack
+func (w *recoverResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	w.wrote = true
+	return h.Hijack()
+}
+
+func (w *recoverResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.wrote = true
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+}
+
+func (w *recoverResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recoverPanic recovers panic then sends PanicError through error pipeline,
+// must be called directly with defer.
+//
+// If the response already started (i.e. SSE stream) the error can not be encoded,
+// the error hooks still run then the connection is aborted with http.ErrAbortHandler
+func (m *Manager) recoverPanic(w *recoverResponseWriter, r *http.Request, req *any) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+
+	err := &PanicError{Value: p, Stack: debug.Stack()}
+	if !w.wrote {
+		m.encodeAndHookError(w, r, *req, err)
+		return
+	}
+
+	m.hookError(w, r, *req, m.withErrorID(m.wrapError(err)))
+	panic(http.ErrAbortHandler)
+}
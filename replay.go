@@ -0,0 +1,180 @@
+package arpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// SSEReplay is the bounded per topic buffer of sse messages,
+// it re-sends messages missed since client's last event id before live messages,
+// topic is kept until Remove called
+type SSEReplay struct {
+	size   int
+	mu     sync.Mutex
+	topics map[string]*replayTopic
+}
+
+type replayTopic struct {
+	seq     uint64
+	msgs    []replayMessage
+	notify  chan struct{} // closed when new message added
+	subs    int           // serving streams
+	removed bool          // removed by Remove
+}
+
+type replayMessage struct {
+	seq uint64
+	msg SSEMessage
+}
+
+// NewSSEReplay creates new replay buffer that keeps the last size messages per topic
+func NewSSEReplay(size int) *SSEReplay {
+	if size <= 0 {
+		panic("arpc: replay size must be positive")
+	}
+	return &SSEReplay{
+		size:   size,
+		topics: make(map[string]*replayTopic),
+	}
+}
+
+func (b *SSEReplay) topic(name string) *replayTopic {
+	t := b.topics[name]
+	if t == nil {
+		t = &replayTopic{notify: make(chan struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Add adds message to topic then wakes up serving streams,
+// empty message id is set to the topic sequence number
+func (b *SSEReplay) Add(topic string, msg SSEMessage) SSEMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	t.seq++
+	if msg.ID == "" {
+		msg.ID = strconv.FormatUint(t.seq, 10)
+	}
+	if len(t.msgs) >= b.size {
+		copy(t.msgs, t.msgs[1:])
+		t.msgs = t.msgs[:len(t.msgs)-1]
+	}
+	t.msgs = append(t.msgs, replayMessage{t.seq, msg})
+
+	close(t.notify)
+	t.notify = make(chan struct{})
+	return msg
+}
+
+// Remove removes topic and its buffered messages then ends serving streams of the topic,
+// call it when per user or per resource topic is no longer used
+func (b *SSEReplay) Remove(topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topics[topic]
+	if t == nil {
+		return
+	}
+	delete(b.topics, topic)
+	t.removed = true
+	close(t.notify)
+}
+
+// release removes topic created by serving stream when no message added
+func (b *SSEReplay) release(name string, t *replayTopic) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t.subs--
+	if t.subs == 0 && len(t.msgs) == 0 && b.topics[name] == t {
+		delete(b.topics, name)
+	}
+}
+
+// Since returns messages in topic after lastID,
+// returns all buffered messages if lastID already dropped from buffer
+func (b *SSEReplay) Since(topic, lastID string) []SSEMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topics[topic]
+	if t == nil {
+		return nil
+	}
+	var msgs []SSEMessage
+	for _, m := range t.after(t.cursor(lastID)) {
+		msgs = append(msgs, m.msg)
+	}
+	return msgs
+}
+
+// cursor returns sequence number of message id,
+// unknown id returns sequence before the oldest buffered message
+func (t *replayTopic) cursor(id string) uint64 {
+	for _, m := range t.msgs {
+		if m.msg.ID == id {
+			return m.seq
+		}
+	}
+	if len(t.msgs) == 0 {
+		return t.seq
+	}
+	return t.msgs[0].seq - 1
+}
+
+func (t *replayTopic) after(seq uint64) []replayMessage {
+	for i, m := range t.msgs {
+		if m.seq > seq {
+			return append([]replayMessage(nil), t.msgs[i:]...)
+		}
+	}
+	return nil
+}
+
+// Serve replays messages in topic missed since w.LastEventID,
+// then writes new messages until ctx done or topic removed
+func (b *SSEReplay) Serve(ctx context.Context, w SSEResponseWriter, topic string) error {
+	b.mu.Lock()
+	t := b.topic(topic)
+	t.subs++
+	cursor := t.seq
+	if id := w.LastEventID(); id != "" {
+		cursor = t.cursor(id)
+	}
+	b.mu.Unlock()
+	defer b.release(topic, t)
+
+	// send header to client before wait for messages
+	w.WriteHeader(http.StatusOK)
+	for {
+		b.mu.Lock()
+		msgs := t.after(cursor)
+		notify := t.notify
+		removed := t.removed
+		b.mu.Unlock()
+
+		for _, m := range msgs {
+			err := w.WriteMessage(m.msg)
+			if err != nil {
+				return err
+			}
+			cursor = m.seq
+		}
+		w.Flush()
+		if removed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package arpc
+
+import (
+	"net/http"
+)
+
+// StatusResult implements by result to change response status,
+// zero status is 200
+type StatusResult interface {
+	ResponseStatus() int
+}
+
+// HeaderResult implements by result to add response headers
+type HeaderResult interface {
+	ResponseHeaders() http.Header
+}
+
+// CookieResult implements by result to set response cookies
+type CookieResult interface {
+	ResponseCookies() []*http.Cookie
+}
+
+// OnBeforeWrite calls f before encode ok response,
+// f can modify response headers
+func (m *Manager) OnBeforeWrite(f func(w http.ResponseWriter, r *http.Request, req any, res any)) {
+	m.onBeforeWriteFuncs = append(m.onBeforeWriteFuncs, f)
+}
+
+func (m *Manager) hookBeforeWrite(w http.ResponseWriter, r *http.Request, req, res any) {
+	for _, f := range m.onBeforeWriteFuncs {
+		f(w, r, req, res)
+	}
+}
+
+// writeResultHeaders writes headers and cookies from result
+func writeResultHeaders(w http.ResponseWriter, res any) {
+	if p, ok := res.(HeaderResult); ok {
+		h := w.Header()
+		for k, vs := range p.ResponseHeaders() {
+			for _, v := range vs {
+				h.Add(k, v)
+			}
+		}
+	}
+	if p, ok := res.(CookieResult); ok {
+		for _, c := range p.ResponseCookies() {
+			http.SetCookie(w, c)
+		}
+	}
+}
+
+func resultStatus(res any) int {
+	if p, ok := res.(StatusResult); ok {
+		if status := p.ResponseStatus(); status != 0 {
+			return status
+		}
+	}
+	return http.StatusOK
+}
@@ -1,8 +1,15 @@
 package arpc
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type SSEResponseWriter interface {
@@ -10,24 +17,97 @@ type SSEResponseWriter interface {
 	http.Flusher
 	WriteEvent(event, data string) error
 	WriteData(data string) error
+	WriteMessage(msg SSEMessage) error
+	WriteComment(comment string) error
+
+	// LastEventID returns Last-Event-ID header or lastEventId query sent by reconnecting client
+	LastEventID() string
+}
+
+// SSEMessage is the server-sent event message
+type SSEMessage struct {
+	ID    string        // last event id, sets to client for resumption
+	Event string        // event name, empty is message
+	Data  string        // data, multiline data sends as multiple data lines
+	Retry time.Duration // reconnection time, zero does not send
+}
+
+// ErrSSEInvalidField is the error when sse event name or id contains line break
+var ErrSSEInvalidField = errors.New("arpc: sse field contains line break")
+
+// isSSEField returns true if s can be written as single sse field
+func isSSEField(s string) bool {
+	return !strings.ContainsAny(s, "\r\n\x00")
 }
 
+// sseLines normalizes CRLF and CR line breaks to LF
+var sseLines = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
 var _ SSEResponseWriter = (*sseResponseWriter)(nil)
 
+// sseResponseWriter is safe for concurrent writes
 type sseResponseWriter struct {
-	wrote bool
-	w     http.ResponseWriter
+	mu          sync.Mutex
+	wrote       bool
+	closed      bool
+	w           http.ResponseWriter
+	lastEventID string
+	heartbeat   time.Duration
+	timer       *time.Timer
+	stop        func() bool
 }
 
-func newSSEResponseWriter(w http.ResponseWriter) SSEResponseWriter {
-	return &sseResponseWriter{w: w}
+func newSSEResponseWriter(w http.ResponseWriter, r *http.Request, heartbeat time.Duration) *sseResponseWriter {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("lastEventId")
+	}
+	sw := &sseResponseWriter{w: w, lastEventID: id, heartbeat: heartbeat}
+	if heartbeat > 0 {
+		// heartbeat starts before the first write to keep idle stream alive,
+		// the header is written lazily by the first write
+		sw.mu.Lock()
+		sw.timer = time.AfterFunc(heartbeat, sw.beat)
+		sw.stop = context.AfterFunc(r.Context(), sw.close)
+		sw.mu.Unlock()
+	}
+	return sw
 }
 
-func (w *sseResponseWriter) writeHeader() {
-	if w.wrote {
+// end stops heartbeat then returns true if the response started
+func (w *sseResponseWriter) end() bool {
+	w.close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.wrote
+}
+
+// close stops heartbeat, must call after handler returned
+func (w *sseResponseWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	if w.stop != nil {
+		w.stop()
+	}
+}
+
+// beat sends heartbeat comment
+func (w *sseResponseWriter) beat() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	w.write([]byte(": heartbeat\n\n"))
+	w.flush()
 }
 
 func (w *sseResponseWriter) Header() http.Header {
@@ -35,6 +115,14 @@ func (w *sseResponseWriter) Header() http.Header {
 }
 
 func (w *sseResponseWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeHeader(statusCode)
+}
+
+// writeHeader writes header, must hold mu
+func (w *sseResponseWriter) writeHeader(statusCode int) {
 	if w.wrote {
 		return
 	}
@@ -43,36 +131,179 @@ func (w *sseResponseWriter) WriteHeader(statusCode int) {
 	w.w.WriteHeader(statusCode)
 }
 
+// write writes b then delays next heartbeat, must hold mu
+func (w *sseResponseWriter) write(b []byte) (int, error) {
+	w.writeHeader(http.StatusOK)
+	n, err := w.w.Write(b)
+	if w.timer != nil && !w.closed {
+		w.timer.Reset(w.heartbeat)
+	}
+	return n, err
+}
+
 func (w *sseResponseWriter) Write(b []byte) (int, error) {
-	w.writeHeader()
-	return w.w.Write(b)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.write(b)
 }
 
-func (w *sseResponseWriter) writeData(data string) error {
-	for _, x := range strings.Split(data, "\n") {
-		_, err := w.w.Write([]byte("data: " + x + "\n"))
-		if err != nil {
-			return err
-		}
+func writeSSEData(b *strings.Builder, data string) {
+	for _, x := range strings.Split(sseLines.Replace(data), "\n") {
+		b.WriteString("data: ")
+		b.WriteString(x)
+		b.WriteString("\n")
 	}
-	_, err := w.w.Write([]byte("\n"))
-	return err
 }
 
 func (w *sseResponseWriter) WriteEvent(event, data string) error {
-	w.writeHeader()
-	_, err := w.w.Write([]byte("event: " + event + "\n"))
-	if err != nil {
-		return err
-	}
-	return w.writeData(data)
+	return w.WriteMessage(SSEMessage{Event: event, Data: data})
 }
 
 func (w *sseResponseWriter) WriteData(data string) error {
-	w.writeHeader()
-	return w.writeData(data)
+	return w.WriteMessage(SSEMessage{Data: data})
+}
+
+// WriteMessage writes message, returns ErrSSEInvalidField if id or event contains line break
+func (w *sseResponseWriter) WriteMessage(msg SSEMessage) error {
+	if !isSSEField(msg.ID) || !isSSEField(msg.Event) {
+		return ErrSSEInvalidField
+	}
+
+	var b strings.Builder
+	if msg.ID != "" {
+		b.WriteString("id: " + msg.ID + "\n")
+	}
+	if msg.Event != "" {
+		b.WriteString("event: " + msg.Event + "\n")
+	}
+	if msg.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(msg.Retry.Milliseconds(), 10) + "\n")
+	}
+	// id or retry only message does not have data
+	if msg.Data != "" || msg.Event != "" || (msg.ID == "" && msg.Retry <= 0) {
+		writeSSEData(&b, msg.Data)
+	}
+	b.WriteString("\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.write([]byte(b.String()))
+	return err
+}
+
+// WriteComment writes comment lines, client ignores comments
+func (w *sseResponseWriter) WriteComment(comment string) error {
+	var b strings.Builder
+	for _, x := range strings.Split(sseLines.Replace(comment), "\n") {
+		b.WriteString(": " + x + "\n")
+	}
+	b.WriteString("\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.write([]byte(b.String()))
+	return err
+}
+
+func (w *sseResponseWriter) LastEventID() string {
+	return w.lastEventID
 }
 
 func (w *sseResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+}
+
+func (w *sseResponseWriter) flush() {
 	w.w.(http.Flusher).Flush()
 }
+
+// SSEEvent implements by value sent through channel or send function
+// to set event name and id
+type SSEEvent interface {
+	SSEEvent() (event, id string)
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// isSSESendFunc returns true if t is func(T) error
+func isSSESendFunc(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() == 1 && t.NumOut() == 1 && t.Out(0) == errorType
+}
+
+// toSSEMessage encodes v as JSON data message,
+// SSEMessage value sends as is
+func toSSEMessage(v any) (SSEMessage, error) {
+	if msg, ok := v.(SSEMessage); ok {
+		return msg, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return SSEMessage{}, err
+	}
+	msg := SSEMessage{Data: string(data)}
+	if e, ok := v.(SSEEvent); ok {
+		msg.Event, msg.ID = e.SSEEvent()
+	}
+	return msg, nil
+}
+
+// send writes v as message then flushes
+func (w *sseResponseWriter) send(ctx context.Context, v any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := toSSEMessage(v)
+	if err != nil {
+		return err
+	}
+	err = w.WriteMessage(msg)
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
+
+// sendFunc creates send function of type t
+func (w *sseResponseWriter) sendFunc(ctx context.Context, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		err := w.send(ctx, args[0].Interface())
+		return []reflect.Value{reflect.ValueOf(&err).Elem()}
+	})
+}
+
+// serveEvents sends values from channel res as sse messages
+// until the channel closed or client disconnected
+func (m *Manager) serveEvents(w http.ResponseWriter, r *http.Request, req, res any) {
+	ctx := r.Context()
+	sw := newSSEResponseWriter(w, r, m.SSEHeartbeat)
+	defer sw.close()
+
+	m.hookBeforeWrite(w, r, req, res)
+	sw.WriteHeader(http.StatusOK)
+	sw.Flush()
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(res)},
+	}
+	for {
+		i, v, ok := reflect.Select(cases)
+		if i == 0 || !ok {
+			break
+		}
+		err := sw.send(ctx, v.Interface())
+		if err != nil {
+			m.hookError(w, r, req, m.withErrorID(m.wrapError(err)))
+			return
+		}
+	}
+	m.hookOK(w, r, req, nil)
+}
@@ -0,0 +1,206 @@
+// Package sse fans out server-sent events to arpc sse handlers
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/acoshift/arpc/v2"
+)
+
+// Policy is the slow consumer policy when subscriber queue is full
+type Policy int
+
+// policies
+const (
+	DropOldest Policy = iota // drops the oldest queued message
+	DropNewest               // drops the published message
+	Disconnect               // closes the subscription
+)
+
+// ErrSlowConsumer is the error when subscription closed by Disconnect policy
+var ErrSlowConsumer = errors.New("sse: slow consumer")
+
+// DefaultBufferSize is the subscriber queue size when Broker.BufferSize is zero
+const DefaultBufferSize = 16
+
+// Broker publishes messages to subscribers of a topic,
+// zero value is ready to use
+type Broker struct {
+	BufferSize int    // subscriber queue size
+	Policy     Policy // slow consumer policy
+
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+// Subscription is the subscription to a topic
+type Subscription struct {
+	b     *Broker
+	topic string
+	ch    chan arpc.SSEMessage
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+	stop   func() bool
+}
+
+func (b *Broker) bufferSize() int {
+	if b.BufferSize <= 0 {
+		return DefaultBufferSize
+	}
+	return b.BufferSize
+}
+
+// Subscribe subscribes to topic,
+// the subscription is unsubscribed when ctx done
+func (b *Broker) Subscribe(ctx context.Context, topic string) *Subscription {
+	s := &Subscription{
+		b:     b,
+		topic: topic,
+		ch:    make(chan arpc.SSEMessage, b.bufferSize()),
+	}
+
+	b.mu.Lock()
+	if b.topics == nil {
+		b.topics = make(map[string]map[*Subscription]struct{})
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*Subscription]struct{})
+	}
+	b.topics[topic][s] = struct{}{}
+	b.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, s.Unsubscribe)
+	s.mu.Lock()
+	s.stop = stop
+	s.mu.Unlock()
+	return s
+}
+
+// Publish sends msg to all subscribers of topic without blocking,
+// full subscriber queue is handled by Policy
+func (b *Broker) Publish(topic string, msg arpc.SSEMessage) {
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for s := range b.topics[topic] {
+		if !s.push(msg, b.Policy) {
+			slow = append(slow, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range slow {
+		s.close(ErrSlowConsumer)
+	}
+}
+
+// Subscribers returns number of subscribers of topic
+func (b *Broker) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.topics[topic])
+}
+
+func (b *Broker) remove(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.topics[s.topic]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(b.topics, s.topic)
+	}
+}
+
+// Serve subscribes to topic then writes messages to w until ctx done,
+// returns ErrSlowConsumer if the subscription closed by Disconnect policy,
+// arpc ends the stream then sends the error to OnError hooks without encoding
+func (b *Broker) Serve(ctx context.Context, w arpc.SSEResponseWriter, topic string) error {
+	s := b.Subscribe(ctx, topic)
+	defer s.Unsubscribe()
+
+	// send header to client before wait for messages
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+	for msg := range s.C() {
+		err := w.WriteMessage(msg)
+		if err != nil {
+			return err
+		}
+		w.Flush()
+	}
+	return s.Err()
+}
+
+// push queues msg, returns false if subscriber must disconnect
+func (s *Subscription) push(msg arpc.SSEMessage, policy Policy) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return true
+	}
+	select {
+	case s.ch <- msg:
+		return true
+	default:
+	}
+
+	switch policy {
+	case DropNewest:
+	case Disconnect:
+		return false
+	default:
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- msg:
+		default:
+		}
+	}
+	return true
+}
+
+// C returns the channel of messages,
+// the channel is closed when unsubscribed
+func (s *Subscription) C() <-chan arpc.SSEMessage {
+	return s.ch
+}
+
+// Err returns ErrSlowConsumer if the subscription closed by Disconnect policy
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Unsubscribe removes the subscription from broker then closes the channel
+func (s *Subscription) Unsubscribe() {
+	s.close(nil)
+}
+
+func (s *Subscription) close(err error) {
+	s.b.remove(s)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = err
+	close(s.ch)
+	if s.stop != nil {
+		s.stop()
+	}
+}
@@ -0,0 +1,183 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/acoshift/arpc/v2"
+	"github.com/acoshift/arpc/v2/sse"
+)
+
+func data(msgs <-chan arpc.SSEMessage) []string {
+	var xs []string
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return xs
+			}
+			xs = append(xs, msg.Data)
+		default:
+			return xs
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Publish", func(t *testing.T) {
+		var b sse.Broker
+		s1 := b.Subscribe(context.Background(), "a")
+		s2 := b.Subscribe(context.Background(), "a")
+		s3 := b.Subscribe(context.Background(), "b")
+		assert.Equal(t, 2, b.Subscribers("a"))
+
+		b.Publish("a", arpc.SSEMessage{Data: "1"})
+		assert.Equal(t, []string{"1"}, data(s1.C()))
+		assert.Equal(t, []string{"1"}, data(s2.C()))
+		assert.Empty(t, data(s3.C()))
+
+		s1.Unsubscribe()
+		_, ok := <-s1.C()
+		assert.False(t, ok)
+		assert.Equal(t, 1, b.Subscribers("a"))
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		var b sse.Broker
+		ctx, cancel := context.WithCancel(context.Background())
+		s := b.Subscribe(ctx, "a")
+		cancel()
+
+		_, ok := <-s.C()
+		assert.False(t, ok)
+		assert.NoError(t, s.Err())
+		assert.Equal(t, 0, b.Subscribers("a"))
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		b := sse.Broker{BufferSize: 2, Policy: sse.DropOldest}
+		s := b.Subscribe(context.Background(), "a")
+		for _, x := range []string{"1", "2", "3"} {
+			b.Publish("a", arpc.SSEMessage{Data: x})
+		}
+		assert.Equal(t, []string{"2", "3"}, data(s.C()))
+	})
+
+	t.Run("DropNewest", func(t *testing.T) {
+		b := sse.Broker{BufferSize: 2, Policy: sse.DropNewest}
+		s := b.Subscribe(context.Background(), "a")
+		for _, x := range []string{"1", "2", "3"} {
+			b.Publish("a", arpc.SSEMessage{Data: x})
+		}
+		assert.Equal(t, []string{"1", "2"}, data(s.C()))
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		b := sse.Broker{BufferSize: 2, Policy: sse.Disconnect}
+		s := b.Subscribe(context.Background(), "a")
+		for _, x := range []string{"1", "2", "3"} {
+			b.Publish("a", arpc.SSEMessage{Data: x})
+		}
+		assert.Equal(t, []string{"1", "2"}, data(s.C()))
+		assert.ErrorIs(t, s.Err(), sse.ErrSlowConsumer)
+		assert.Equal(t, 0, b.Subscribers("a"))
+	})
+}
+
+func TestBrokerServe(t *testing.T) {
+	t.Parallel()
+
+	var b sse.Broker
+	m := arpc.New()
+	srv := httptest.NewServer(m.Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+		return b.Serve(ctx, w, "news")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	resp, err := http.DefaultClient.Do(r)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// header is sent after subscribed
+	assert.Equal(t, 1, b.Subscribers("news"))
+	b.Publish("news", arpc.SSEMessage{Event: "news", Data: "hello"})
+
+	scanner := bufio.NewScanner(resp.Body)
+	var lines []string
+	for len(lines) < 2 && scanner.Scan() {
+		if s := scanner.Text(); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	assert.Equal(t, "event: news\ndata: hello", strings.Join(lines, "\n"))
+
+	cancel()
+	assert.Eventually(t, func() bool { return b.Subscribers("news") == 0 }, time.Second, time.Millisecond)
+}
+
+// publishOnHeader publishes before Serve reads the subscription
+type publishOnHeader struct {
+	arpc.SSEResponseWriter
+	publish func()
+}
+
+func (w publishOnHeader) WriteHeader(statusCode int) {
+	w.publish()
+	w.SSEResponseWriter.WriteHeader(statusCode)
+}
+
+func TestBrokerServeSlowConsumer(t *testing.T) {
+	t.Parallel()
+
+	b := sse.Broker{BufferSize: 1, Policy: sse.Disconnect}
+	m := arpc.New()
+	var hooked error
+	m.OnError(func(w http.ResponseWriter, r *http.Request, req any, err error) {
+		hooked = err
+	})
+	h := m.Handler(func(ctx context.Context, w arpc.SSEResponseWriter) error {
+		return b.Serve(ctx, publishOnHeader{w, func() {
+			b.Publish("a", arpc.SSEMessage{Data: "1"})
+			b.Publish("a", arpc.SSEMessage{Data: "2"})
+		}}, "a")
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "data: 1\n\n", w.Body.String())
+	assert.ErrorIs(t, hooked, sse.ErrSlowConsumer)
+}
+
+func TestBrokerChannel(t *testing.T) {
+	t.Parallel()
+
+	var b sse.Broker
+	m := arpc.New()
+	h := m.Handler(func(ctx context.Context) (<-chan arpc.SSEMessage, error) {
+		s := b.Subscribe(ctx, "a")
+		b.Publish("a", arpc.SSEMessage{ID: "1", Data: "x"})
+		s.Unsubscribe()
+		return s.C(), nil
+	})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "id: 1\ndata: x\n\n", w.Body.String())
+}
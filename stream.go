@@ -0,0 +1,194 @@
+package arpc
+
+import (
+	"io"
+	"io/fs"
+	"maps"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// StreamResult implements by result to send raw response body instead of encode result,
+// *os.File and fs.File results also send as stream
+type StreamResult interface {
+	// ContentType returns response content type,
+	// empty detects from filename
+	ContentType() string
+
+	// Filename returns filename for Content-Disposition header,
+	// empty sends without Content-Disposition
+	Filename() string
+
+	// ContentLength returns body length, -1 if unknown
+	ContentLength() int64
+
+	// Body returns response body, io.ReadSeeker body supports range request
+	// when the result does not change status by StatusResult,
+	// io.Closer body is closed after sent
+	Body() (io.Reader, error)
+}
+
+var (
+	streamResultType = reflect.TypeFor[StreamResult]()
+	fsFileType       = reflect.TypeFor[fs.File]()
+)
+
+// isStreamType returns true if t sends as stream
+func isStreamType(t reflect.Type) bool {
+	return t.Implements(streamResultType) || t.Implements(fsFileType)
+}
+
+// NewStream creates new StreamResult from reader,
+// length is -1 if unknown
+func NewStream(contentType, filename string, length int64, r io.Reader) StreamResult {
+	return &stream{contentType, filename, length, r}
+}
+
+type stream struct {
+	contentType string
+	filename    string
+	length      int64
+	r           io.Reader
+}
+
+func (s *stream) ContentType() string      { return s.contentType }
+func (s *stream) Filename() string         { return s.filename }
+func (s *stream) ContentLength() int64     { return s.length }
+func (s *stream) Body() (io.Reader, error) { return s.r, nil }
+
+// fileStream sends fs.File as StreamResult
+type fileStream struct {
+	f  fs.File
+	fi fs.FileInfo
+}
+
+func (s *fileStream) ContentType() string      { return "" }
+func (s *fileStream) Filename() string         { return s.fi.Name() }
+func (s *fileStream) ContentLength() int64     { return s.fi.Size() }
+func (s *fileStream) Body() (io.Reader, error) { return s.f, nil }
+
+// streamWriter writes status on first write
+type streamWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.wrote = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// serveStream sends stream result,
+// error before the first byte restores headers then sends through error encoder,
+// error after the response started aborts the connection
+func (m *Manager) serveStream(w http.ResponseWriter, r *http.Request, req, res any) {
+	var s StreamResult
+	switch p := res.(type) {
+	case StreamResult:
+		s = p
+	case fs.File:
+		fi, err := p.Stat()
+		if err != nil {
+			p.Close()
+			m.encodeAndHookError(w, r, req, err)
+			return
+		}
+		s = &fileStream{p, fi}
+	}
+
+	body, err := s.Body()
+	if err != nil {
+		m.encodeAndHookError(w, r, req, err)
+		return
+	}
+	if c, ok := body.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	var (
+		size    = s.ContentLength()
+		status  = resultStatus(res)
+		modTime time.Time
+	)
+	// ServeContent decides status from range and conditional request,
+	// result that changes status sends as non-seekable stream
+	rs, seekable := body.(io.ReadSeeker)
+	seekable = seekable && status == http.StatusOK
+	if seekable {
+		// check body can seek before write response
+		_, err = rs.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = rs.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			m.encodeAndHookError(w, r, req, err)
+			return
+		}
+	}
+	if fst, ok := s.(*fileStream); ok {
+		modTime = fst.fi.ModTime()
+	}
+
+	h := w.Header()
+	orig := h.Clone()
+	ct := s.ContentType()
+	if ct == "" {
+		ct = mime.TypeByExtension(path.Ext(s.Filename()))
+	}
+	if ct == "" && !seekable {
+		ct = "application/octet-stream"
+	}
+	if ct != "" {
+		h.Set("Content-Type", ct)
+	}
+	if name := s.Filename(); name != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	writeResultHeaders(w, res)
+	m.hookBeforeWrite(w, r, req, res)
+
+	if seekable {
+		// ServeContent handles range and conditional requests
+		http.ServeContent(w, r, s.Filename(), modTime, rs)
+		m.hookOK(w, r, req, res)
+		return
+	}
+
+	if size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	sw := &streamWriter{ResponseWriter: w, status: status}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(sw.status)
+		m.hookOK(w, r, req, res)
+		return
+	}
+	if wt, ok := body.(io.WriterTo); ok {
+		_, err = wt.WriteTo(sw)
+	} else {
+		_, err = io.Copy(sw, body)
+	}
+	if err != nil {
+		if !sw.wrote {
+			// drop headers from result and hooks for error response
+			clear(h)
+			maps.Copy(h, orig)
+			m.encodeAndHookError(w, r, req, err)
+			return
+		}
+		m.hookError(w, r, req, m.withErrorID(m.wrapError(err)))
+		panic(http.ErrAbortHandler)
+	}
+	if !sw.wrote {
+		w.WriteHeader(sw.status)
+	}
+	m.hookOK(w, r, req, res)
+}
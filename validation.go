@@ -0,0 +1,107 @@
+package arpc
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FieldError is the validation error of a field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// NewFieldError creates new FieldError
+func NewFieldError(field, code, message string) error {
+	return &FieldError{Field: field, Code: code, Message: message}
+}
+
+// Error implements error
+func (err *FieldError) Error() string {
+	if err.Field == "" {
+		return err.Message
+	}
+	return err.Field + ": " + err.Message
+}
+
+// ValidationErrorCode is the code of ValidationError
+const ValidationErrorCode = "validation"
+
+// ValidationError contains errors of all invalid fields,
+// always return 200 status with false ok value
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// NewValidationError creates new ValidationError from field errors
+func NewValidationError(fields ...*FieldError) error {
+	return &ValidationError{Fields: fields}
+}
+
+// OKError implements OKError
+func (err *ValidationError) OKError() {}
+
+// Error implements error
+func (err *ValidationError) Error() string {
+	msgs := make([]string, 0, len(err.Fields))
+	for _, f := range err.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return ValidationErrorCode + " " + strings.Join(msgs, "; ")
+}
+
+// Message returns error message
+func (err *ValidationError) Message() string {
+	if len(err.Fields) == 1 {
+		return err.Fields[0].Message
+	}
+	return "invalid fields"
+}
+
+// Add adds field error
+func (err *ValidationError) Add(field, code, message string) {
+	err.Fields = append(err.Fields, &FieldError{Field: field, Code: code, Message: message})
+}
+
+// MarshalJSON implements json.Marshaler
+func (err *ValidationError) MarshalJSON() ([]byte, error) {
+	fields := err.Fields
+	if fields == nil {
+		fields = []*FieldError{}
+	}
+	return json.Marshal(struct {
+		Code    string        `json:"code"`
+		Message string        `json:"message"`
+		Fields  []*FieldError `json:"fields"`
+	}{ValidationErrorCode, err.Message(), fields})
+}
+
+// toValidationError converts field error and errors joined with errors.Join into ValidationError,
+// returns err as is if it contains non-user error
+func toValidationError(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *ValidationError:
+		return e
+	case *FieldError:
+		return &ValidationError{Fields: []*FieldError{e}}
+	case interface{ Unwrap() []error }:
+		var verr ValidationError
+		for _, x := range e.Unwrap() {
+			switch x := toValidationError(x).(type) {
+			case *ValidationError:
+				verr.Fields = append(verr.Fields, x.Fields...)
+			case *Error:
+				verr.Fields = append(verr.Fields, &FieldError{Code: x.Code(), Message: x.Message()})
+			case OKError:
+				verr.Fields = append(verr.Fields, &FieldError{Message: x.(error).Error()})
+			default:
+				return err
+			}
+		}
+		return &verr
+	}
+	return err
+}
@@ -0,0 +1,409 @@
+package arpc
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const tagValidate = "validate"
+
+// RuleFunc validates field value v with rule parameter,
+// returns error with message to show to user when v is invalid
+type RuleFunc func(v reflect.Value, param string) error
+
+// RegisterRule registers validate rule,
+// the rule can be used in validate tag i.e. `validate:"required,name=param"`
+func (m *Manager) RegisterRule(name string, f RuleFunc) {
+	if m.rules == nil {
+		m.rules = make(map[string]RuleFunc)
+	}
+	m.rules[name] = f
+	m.validateCache.Clear()
+}
+
+// rule returns rule func, returns false if rule is unknown
+func (m *Manager) rule(name string) (RuleFunc, bool) {
+	if f, ok := m.rules[name]; ok {
+		return f, true
+	}
+	f, ok := defaultRules[name]
+	return f, ok
+}
+
+func (m *Manager) validateTag() string {
+	if m.ValidateTag == "" {
+		return tagValidate
+	}
+	return m.ValidateTag
+}
+
+var defaultRules = map[string]RuleFunc{
+	"min":   ruleMin,
+	"max":   ruleMax,
+	"len":   ruleLen,
+	"email": ruleEmail,
+	"url":   ruleURL,
+	"oneof": ruleOneOf,
+}
+
+// ruleChecks checks built-in rule parameter and field type when validate fields cached
+var ruleChecks = map[string]func(t reflect.Type, param string) error{
+	"min":   checkSizeRule,
+	"max":   checkSizeRule,
+	"len":   checkSizeRule,
+	"email": checkStringRule,
+	"url":   checkStringRule,
+	"oneof": checkOneOfRule,
+}
+
+// checkRule checks rule on field type t,
+// custom rule is not checked
+func (m *Manager) checkRule(t reflect.Type, name, param string) error {
+	if _, ok := m.rules[name]; ok {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if check := ruleChecks[name]; check != nil {
+		return check(t, param)
+	}
+	return nil
+}
+
+func checkSizeRule(t reflect.Type, param string) error {
+	_, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid param %q", param)
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", t)
+}
+
+func checkStringRule(t reflect.Type, param string) error {
+	if t.Kind() != reflect.String {
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+func checkOneOfRule(t reflect.Type, param string) error {
+	if len(strings.Fields(param)) == 0 {
+		return fmt.Errorf("invalid param %q", param)
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", t)
+}
+
+type validateRule struct {
+	name  string
+	param string
+	f     RuleFunc
+}
+
+type validateField struct {
+	index     int
+	name      string
+	rules     []validateRule
+	required  bool
+	omitEmpty bool // skip rules for empty value
+	embedded  bool
+}
+
+// validateFields returns fields of struct t with resolved rules,
+// unknown rules are skipped i.e. rules for other validator that shares the tag,
+// panics if built-in rule has invalid param or does not support the field type
+func (m *Manager) validateFields(t reflect.Type) []validateField {
+	if fs, ok := m.validateCache.Load(t); ok {
+		return fs.([]validateField)
+	}
+
+	var fs []validateField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		f := validateField{
+			index:    i,
+			name:     name,
+			embedded: sf.Anonymous && name == "",
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+
+		if tag := sf.Tag.Get(m.validateTag()); tag != "" && tag != "-" {
+			for _, x := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(x), "=")
+				switch name {
+				case "":
+				case "required":
+					f.required = true
+				case "omitempty":
+					f.omitEmpty = true
+				default:
+					rf, ok := m.rule(name)
+					if !ok {
+						continue
+					}
+					if err := m.checkRule(sf.Type, name, param); err != nil {
+						panic(fmt.Sprintf("arpc: invalid validate rule %s on %s.%s: %v", name, t, sf.Name, err))
+					}
+					f.rules = append(f.rules, validateRule{name, param, rf})
+				}
+			}
+		}
+		fs = append(fs, f)
+	}
+
+	m.validateCache.Store(t, fs)
+	return fs
+}
+
+// checkValidate builds validate fields of t and nested types,
+// invalid rules panic when handler created instead of per request
+func (m *Manager) checkValidate(t reflect.Type, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || visited[t] {
+		return
+	}
+	visited[t] = true
+	for _, f := range m.validateFields(t) {
+		m.checkValidate(t.Field(f.index).Type, visited)
+	}
+}
+
+// validateTags validates v using validate tags, returns ValidationError if any field invalid
+func (m *Manager) validateTags(v any) error {
+	var verr ValidationError
+	m.validateValue(&verr, reflect.ValueOf(v), "")
+	if len(verr.Fields) > 0 {
+		return &verr
+	}
+	return nil
+}
+
+func (m *Manager) validateValue(verr *ValidationError, v reflect.Value, path string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		for _, f := range m.validateFields(v.Type()) {
+			fv := v.Field(f.index)
+			if f.embedded {
+				m.validateValue(verr, fv, path)
+				continue
+			}
+			fp := joinPath(path, f.name)
+			if !m.validateField(verr, fv, fp, &f) {
+				continue
+			}
+			m.validateValue(verr, fv, fp)
+		}
+	case reflect.Slice, reflect.Array:
+		if !hasValidateElem(v.Type()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			m.validateValue(verr, v.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		if !hasValidateElem(v.Type()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			m.validateValue(verr, iter.Value(), joinPath(path, fmt.Sprint(iter.Key().Interface())))
+		}
+	}
+}
+
+// hasValidateElem returns true if element of slice, array or map type t can contain validate tags,
+// i.e. []byte and []int skip walking every element
+func hasValidateElem(t reflect.Type) bool {
+	switch t.Elem().Kind() {
+	case reflect.Struct:
+		return t.Elem() != timeType
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// validateField validates field's rules, returns true if field is valid
+func (m *Manager) validateField(verr *ValidationError, v reflect.Value, path string, f *validateField) bool {
+	if isEmptyValue(v) {
+		if f.required {
+			verr.Add(path, "required", "required")
+			return false
+		}
+		if f.omitEmpty {
+			return true
+		}
+	}
+
+	// zero value is validated, only nil pointer skips rules
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	for _, r := range f.rules {
+		err := r.f(v, r.param)
+		if err != nil {
+			verr.Add(path, r.name, err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+var errUnsupportedRule = errors.New("unsupported type")
+
+// size returns length of string, slice, map or number value
+func size(v reflect.Value) (float64, bool, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, nil
+	}
+	return 0, false, errUnsupportedRule
+}
+
+func ruleMin(v reflect.Value, param string) error {
+	n, isLen, err := size(v)
+	if err != nil {
+		return err
+	}
+	p, _ := strconv.ParseFloat(param, 64)
+	if n >= p {
+		return nil
+	}
+	if isLen {
+		return fmt.Errorf("length must be at least %s", param)
+	}
+	return fmt.Errorf("must be at least %s", param)
+}
+
+func ruleMax(v reflect.Value, param string) error {
+	n, isLen, err := size(v)
+	if err != nil {
+		return err
+	}
+	p, _ := strconv.ParseFloat(param, 64)
+	if n <= p {
+		return nil
+	}
+	if isLen {
+		return fmt.Errorf("length must be at most %s", param)
+	}
+	return fmt.Errorf("must be at most %s", param)
+}
+
+func ruleLen(v reflect.Value, param string) error {
+	n, _, err := size(v)
+	if err != nil {
+		return err
+	}
+	p, _ := strconv.ParseFloat(param, 64)
+	if n != p {
+		return fmt.Errorf("length must be %s", param)
+	}
+	return nil
+}
+
+func ruleEmail(v reflect.Value, param string) error {
+	if v.Kind() != reflect.String {
+		return errUnsupportedRule
+	}
+	addr, err := mail.ParseAddress(v.String())
+	if err != nil || addr.Address != v.String() {
+		return fmt.Errorf("invalid email")
+	}
+	return nil
+}
+
+func ruleURL(v reflect.Value, param string) error {
+	if v.Kind() != reflect.String {
+		return errUnsupportedRule
+	}
+	u, err := url.Parse(v.String())
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid url")
+	}
+	return nil
+}
+
+func ruleOneOf(v reflect.Value, param string) error {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return errUnsupportedRule
+	}
+	if !slices.Contains(strings.Fields(param), s) {
+		return fmt.Errorf("must be one of %s", strings.Join(strings.Fields(param), ", "))
+	}
+	return nil
+}